@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// githubRepo returns the owner and name of the repository at url
+// if it's hosted on GitHub, github.com or the instance at cfg.githubURL.
+func (cfg *config) githubRepo(url string) (owner, repo string, ok bool) {
+	base := cfg.githubURL + "/"
+	s := strings.SplitN(strings.TrimPrefix(url, base), "/", 2)
+	if len(s) != 2 || s[0] == "" || s[1] == "" || !strings.HasPrefix(url, base) {
+		return "", "", false
+	}
+	return s[0], strings.TrimSuffix(s[1], ".git"), true
+}
+
+// getVanityPackagesAPI is the equivalent of getVanityPackages which reads
+// the repository through the GitHub API rather than cloning it, so neither
+// git nor the go tool are required. Import comments are found by parsing
+// the package clause of each directory's Go files, which means build
+// constraints and nested modules aren't taken into account.
+func getVanityPackagesAPI(ctx context.Context, gh *github.Client, cfg *config, url string) ([]vanityImport, []skippedPackage, error) {
+	owner, repo, ok := cfg.githubRepo(url)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a GitHub repository", url)
+	}
+
+	ref := cfg.repos[url].Commit
+	if ref == "" {
+		var err error
+		ref, err = cfg.defaultBranches.get(url, func() (string, error) {
+			return apiDefaultBranch(ctx, gh, cfg, owner, repo)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("finding default branch: %v", err)
+		}
+	}
+	opt := &github.RepositoryContentGetOptions{Ref: ref}
+
+	var tree *github.Tree
+	err := cfg.retry.do(ctx, func() (err error) {
+		tree, _, err = gh.Git.GetTree(ctx, owner, repo, ref, true)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dirs := make(map[string][]string)
+	modules := make(map[string]string) // module path by directory, read on demand
+	hasOverrides := false
+	hasIgnore := false
+	for _, entry := range tree.Entries {
+		p := entry.GetPath()
+		if entry.GetType() != "blob" {
+			continue
+		}
+		if p == vanity.OverridesFile {
+			hasOverrides = true
+			continue
+		}
+		if p == vanity.IgnoreFile {
+			hasIgnore = true
+			continue
+		}
+		if path.Base(p) == "go.mod" && !ignoredDir(path.Dir(p)) {
+			modules[path.Dir(p)] = ""
+			continue
+		}
+		if vanity.IsGoSource(path.Base(p)) && !ignoredDir(path.Dir(p)) {
+			dirs[path.Dir(p)] = append(dirs[path.Dir(p)], p)
+		}
+	}
+
+	var ignore *vanity.Ignore
+	if hasIgnore {
+		content, err := getContents(ctx, gh, cfg, owner, repo, vanity.IgnoreFile, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := content.GetContent()
+		if err != nil {
+			return nil, nil, err
+		}
+		if ignore, err = vanity.ParseIgnore([]byte(data)); err != nil {
+			return nil, nil, err
+		}
+		if ignore.Skip {
+			debugf("%s: skipped by %s\n", url, vanity.IgnoreFile)
+			return nil, nil, nil
+		}
+	}
+
+	if hasOverrides {
+		content, err := getContents(ctx, gh, cfg, owner, repo, vanity.OverridesFile, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := content.GetContent()
+		if err != nil {
+			return nil, nil, err
+		}
+		overrides, err := vanity.ParseOverrides([]byte(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		imports, skipped, err := overrides.Imports(cfg.scanConfig(), url)
+		if err != nil {
+			return nil, nil, err
+		}
+		imports, ignored := ignore.Filter(imports)
+		skipped = append(skipped, ignored...)
+		vanity.ApplyRoots(imports, cfg.repos[url].Roots)
+		for i := range imports {
+			imports[i].Branch = ref
+		}
+		return imports, skipped, nil
+	}
+
+	var sorted []string
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+
+	var (
+		imports []vanityImport
+		skipped []skippedPackage
+		fset    = token.NewFileSet()
+	)
+	for _, dir := range sorted {
+		var clause vanity.PackageClause
+		for _, file := range dirs[dir] {
+			content, err := getContents(ctx, gh, cfg, owner, repo, file, opt)
+			if err != nil {
+				return nil, nil, err
+			}
+			src, err := content.GetContent()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			c, err := vanity.ParsePackageClause(fset, file, src)
+			if err != nil {
+				continue
+			}
+			if c.Synopsis == "" {
+				c.Synopsis = clause.Synopsis
+			}
+			if clause.Name == "" || c.ImportPath != "" {
+				clause = c
+			}
+			if clause.ImportPath != "" {
+				break
+			}
+		}
+
+		if cfg.moduleOnly || clause.ImportPath == "" {
+			clause.ImportPath, err = moduleImportPath(modules, dir, func(p string) (string, error) {
+				content, err := getContents(ctx, gh, cfg, owner, repo, p, opt)
+				if err != nil {
+					return "", err
+				}
+				return content.GetContent()
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if reason := cfg.scanConfig().SkipReason(clause.ImportPath, clause.Name); reason != "" {
+			name := clause.ImportPath
+			if name == "" {
+				name = path.Join(owner, repo, dir)
+			}
+			skipped = append(skipped, skippedPackage{Path: name, Reason: reason})
+			continue
+		}
+
+		pathLen := 0
+		if dir != "." {
+			pathLen = len(strings.Split(dir, "/"))
+		}
+
+		imports = append(imports, vanityImport{
+			Import:   clause.ImportPath,
+			RepoURL:  url,
+			Branch:   ref,
+			Synopsis: clause.Synopsis,
+			PathLen:  pathLen,
+			Command:  clause.Name == "main",
+		})
+	}
+
+	imports, ignored := ignore.Filter(imports)
+	skipped = append(skipped, ignored...)
+
+	vanity.ApplyRoots(imports, cfg.repos[url].Roots)
+	return imports, skipped, nil
+}
+
+// moduleImportPath returns the import path of the package in dir from
+// the module containing it, or "" if it isn't in a module. modules maps
+// module directories to their paths, which are read with readFile when
+// not yet known.
+func moduleImportPath(modules map[string]string, dir string, readFile func(path string) (string, error)) (string, error) {
+	modDir := ""
+	found := false
+	for d := range modules {
+		if (d == "." || d == dir || strings.HasPrefix(dir, d+"/")) && (!found || len(d) > len(modDir)) {
+			modDir, found = d, true
+		}
+	}
+	if !found {
+		return "", nil
+	}
+
+	if modules[modDir] == "" {
+		data, err := readFile(path.Join(modDir, "go.mod"))
+		if err != nil {
+			return "", err
+		}
+		modules[modDir] = modulePath([]byte(data))
+	}
+	if modules[modDir] == "" {
+		return "", nil
+	}
+	return path.Join(modules[modDir], strings.TrimPrefix(strings.TrimPrefix(dir, modDir), "/")), nil
+}
+
+// getContents returns the contents of the file at path, retrying
+// according to cfg.retry.
+func getContents(ctx context.Context, gh *github.Client, cfg *config, owner, repo, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, error) {
+	var content *github.RepositoryContent
+	err := cfg.retry.do(ctx, func() (err error) {
+		content, _, _, err = gh.Repositories.GetContents(ctx, owner, repo, path, opt)
+		return err
+	})
+	return content, err
+}
+
+// ignoredDir reports whether the go tool ignores packages in dir, a slash
+// separated path relative to the repository root.
+func ignoredDir(dir string) bool {
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "vendor" || elem == "testdata" || strings.HasPrefix(elem, "_") || (strings.HasPrefix(elem, ".") && elem != ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeContentsAPI serves the tree and contents of files as the master
+// branch of github.com/vcabbage/tftp, returning the configuration and
+// client of getVanityPackagesAPI.
+func fakeContentsAPI(t *testing.T, files map[string]string) (*config, *github.Client) {
+	const base = "/api/v3/repos/vcabbage/tftp/"
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc(base+"git/trees/master", func(w http.ResponseWriter, r *http.Request) {
+		var tree struct {
+			Tree []map[string]string `json:"tree"`
+		}
+		for p := range files {
+			tree.Tree = append(tree.Tree, map[string]string{"path": p, "type": "blob"})
+		}
+		json.NewEncoder(w).Encode(tree)
+	})
+	mux.HandleFunc(base+"contents/", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, base+"contents/")]
+		if !ok || r.URL.Query().Get("ref") != "master" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"type":     "file",
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	})
+
+	cfg := testConfig("pack.ag")
+	cfg.githubURL = srv.URL
+	cfg.defaultBranches.set(srv.URL+"/vcabbage/tftp", "master")
+	gh, err := newGitHubClient(cfg, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, gh
+}
+
+func TestGetVanityPackagesAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "import comment",
+			files: map[string]string{
+				"tftp.go":              "// Package tftp implements TFTP.\npackage tftp // import \"pack.ag/tftp\"\n",
+				"netascii/netascii.go": "package netascii /* import \"pack.ag/tftp/netascii\" */\n",
+				"other/other.go":       "package other\n",
+			},
+			want: []string{"pack.ag/tftp", "pack.ag/tftp/netascii"},
+		},
+		{
+			name: "comment after the first file",
+			files: map[string]string{
+				"a.go": "package tftp\n",
+				"b.go": "package tftp // import \"pack.ag/tftp\"\n",
+			},
+			want: []string{"pack.ag/tftp"},
+		},
+		{
+			name: "overrides error",
+			files: map[string]string{
+				"tftp.go":        "package tftp // import \"pack.ag/tftp\"\n",
+				".govanity.json": `{"packages": [{"import": "pack.ag/tftp", "dir": "../tftp"}]}`,
+			},
+			wantErr: `directory "../tftp" is outside of the repository`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, gh := fakeContentsAPI(t, tt.files)
+			imports, _, err := getVanityPackagesAPI(context.Background(), gh, cfg, cfg.githubURL+"/vcabbage/tftp")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("getVanityPackagesAPI() = %v, %v, want error containing %q", imports, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, imprt := range imports {
+				got = append(got, imprt.Import)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imports = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+// branchCache holds the default branch of each repository URL, so that
+// it's only looked up once per run regardless of how many packages or
+// major versions the repository contains.
+type branchCache struct {
+	mu       sync.Mutex
+	branches map[string]string
+	lookups  map[string]*branchLookup // in progress, by URL
+}
+
+// branchLookup is a lookup of a default branch in progress, done is
+// closed once branch and err are set.
+type branchLookup struct {
+	done   chan struct{}
+	branch string
+	err    error
+}
+
+// get returns the cached default branch of url, calling lookup
+// if it hasn't been found yet. Errors aren't cached.
+//
+// The lock isn't held during lookup, so lookups of different URLs run
+// concurrently, while concurrent calls for the same URL wait for and
+// share the result of a single lookup.
+func (c *branchCache) get(url string, lookup func() (string, error)) (string, error) {
+	c.mu.Lock()
+	if branch, ok := c.branches[url]; ok {
+		c.mu.Unlock()
+		return branch, nil
+	}
+	if l, ok := c.lookups[url]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.branch, l.err
+	}
+	l := &branchLookup{done: make(chan struct{})}
+	if c.lookups == nil {
+		c.lookups = make(map[string]*branchLookup)
+	}
+	c.lookups[url] = l
+	c.mu.Unlock()
+
+	l.branch, l.err = lookup()
+
+	c.mu.Lock()
+	delete(c.lookups, url)
+	if l.err == nil {
+		if c.branches == nil {
+			c.branches = make(map[string]string)
+		}
+		c.branches[url] = l.branch
+	}
+	c.mu.Unlock()
+	close(l.done)
+	return l.branch, l.err
+}
+
+// set records branch as the default branch of url, such as when it's
+// known from listing repositories.
+func (c *branchCache) set(url, branch string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.branches == nil {
+		c.branches = make(map[string]string)
+	}
+	c.branches[url] = branch
+}
+
+// headBranch returns the branch checked out in the clone at dir, which is
+// the default branch of the remote following a clone without --branch.
+func headBranch(ctx context.Context, dir string) (string, error) {
+	cmd := commandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// remoteHeadBranch returns the default branch of the remote repository
+// at url without cloning it.
+func remoteHeadBranch(ctx context.Context, cfg *config, url string) (string, error) {
+	cmd := commandContext(ctx, "git", "ls-remote", "--symref", url, "HEAD")
+	cmd.Env = cfg.gitEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// The symbolic ref is reported as "ref: refs/heads/<branch>\tHEAD".
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "ref:" {
+			return strings.TrimPrefix(fields[1], "refs/heads/"), nil
+		}
+	}
+	return "", fmt.Errorf("no HEAD branch reported by %s", url)
+}
+
+// apiDefaultBranch returns the default branch of the GitHub repository
+// owner/repo.
+func apiDefaultBranch(ctx context.Context, gh *github.Client, cfg *config, owner, repo string) (string, error) {
+	var r *github.Repository
+	err := cfg.retry.do(ctx, func() (err error) {
+		r, _, err = gh.Repositories.Get(ctx, owner, repo)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return r.GetDefaultBranch(), nil
+}
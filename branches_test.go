@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBranchCacheOneLookup(t *testing.T) {
+	var (
+		c       branchCache
+		lookups int32
+		wg      sync.WaitGroup
+	)
+	// Every package of a repository asking for its default branch
+	// at once.
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			branch, err := c.get("https://github.com/vcabbage/amqp", func() (string, error) {
+				atomic.AddInt32(&lookups, 1)
+				time.Sleep(10 * time.Millisecond)
+				return "master", nil
+			})
+			if err != nil || branch != "master" {
+				t.Errorf("get() = %q, %v, want master", branch, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if lookups != 1 {
+		t.Errorf("looked up %d times, want 1", lookups)
+	}
+}
+
+func TestBranchCacheErrors(t *testing.T) {
+	var c branchCache
+	lookups := 0
+	lookup := func() (string, error) {
+		lookups++
+		if lookups == 1 {
+			return "", errors.New("unavailable")
+		}
+		return "main", nil
+	}
+
+	if _, err := c.get("https://github.com/vcabbage/amqp", lookup); err == nil {
+		t.Fatal("get() succeeded, want the lookup error")
+	}
+	for i := 0; i < 2; i++ {
+		if branch, err := c.get("https://github.com/vcabbage/amqp", lookup); err != nil || branch != "main" {
+			t.Fatalf("get() = %q, %v, want main", branch, err)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("looked up %d times, want 2", lookups)
+	}
+}
+
+func TestBranchCacheConcurrentURLs(t *testing.T) {
+	var c branchCache
+	// Each lookup waits for the other to start, which deadlocks if
+	// lookups of different URLs are serialized.
+	started := map[string]chan struct{}{
+		"https://github.com/vcabbage/amqp": make(chan struct{}),
+		"https://github.com/vcabbage/tftp": make(chan struct{}),
+	}
+	other := map[string]string{
+		"https://github.com/vcabbage/amqp": "https://github.com/vcabbage/tftp",
+		"https://github.com/vcabbage/tftp": "https://github.com/vcabbage/amqp",
+	}
+
+	errs := make(chan error, len(started))
+	for url := range started {
+		go func(url string) {
+			_, err := c.get(url, func() (string, error) {
+				close(started[url])
+				select {
+				case <-started[other[url]]:
+					return "master", nil
+				case <-time.After(5 * time.Second):
+					return "", errors.New("lookups were serialized")
+				}
+			})
+			errs <- err
+		}(url)
+	}
+	for range started {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
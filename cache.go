@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staleCloneAge is how old a temporary clone left in the cache has to be
+// before it's removed as left over from an interrupted run, rather than
+// being made by another run sharing the cache.
+const staleCloneAge = time.Hour
+
+// cacheLocks serializes the use of each repository's directory of the
+// cache by concurrent scans, keyed by the directory.
+var cacheLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// cacheLockFile is the name of the file in a repository's directory of
+// the cache which locks it against scans by other runs sharing the cache.
+const cacheLockFile = ".lock"
+
+// lockPollInterval is how often a cache directory locked by another run
+// is checked.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockCacheDir locks the cache directory dir, creating it if needed, and
+// returns a function unlocking it. Other runs are locked out by creating
+// its cacheLockFile exclusively. A lock file older than staleCloneAge is
+// assumed to be left by a run which was killed, and is removed.
+func lockCacheDir(ctx context.Context, dir string) (unlock func(), err error) {
+	cacheLocks.mu.Lock()
+	l, ok := cacheLocks.locks[dir]
+	if !ok {
+		if cacheLocks.locks == nil {
+			cacheLocks.locks = make(map[string]*sync.Mutex)
+		}
+		l = new(sync.Mutex)
+		cacheLocks.locks[dir] = l
+	}
+	cacheLocks.mu.Unlock()
+
+	l.Lock()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		l.Unlock()
+		return nil, err
+	}
+	lockFile := filepath.Join(dir, cacheLockFile)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(lockFile)
+				l.Unlock()
+			}, nil
+		}
+		if !os.IsExist(err) {
+			l.Unlock()
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) >= staleCloneAge {
+			os.Remove(lockFile)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			l.Unlock()
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// cachedClone returns the clone of branch of the repository at url, the
+// default branch if empty, or of commit, in cfg.cacheDir. The clone is
+// reused while the remote branch points at the commit it was cloned at,
+// otherwise it's replaced by a new clone made by fetch. It's locked until
+// unlock is called, so that it isn't replaced while being scanned.
+func cachedClone(ctx context.Context, cfg *config, url, branch, commit string, fetch func(dir string) error) (_ string, unlock func(), err error) {
+	sha := commit
+	if branch != "" || commit == "" {
+		err := cfg.retry.do(ctx, func() (err error) {
+			sha, err = remoteCommit(ctx, cfg, cfg.cloneURL(url), branch)
+			return err
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("finding remote commit: %v", err)
+		}
+	}
+
+	// Sparse clones are missing files which other clones need.
+	mode := "full"
+	if cfg.sparse {
+		mode = "sparse"
+	}
+	sum := sha256.Sum256([]byte(url + "\x00" + branch + "\x00" + mode))
+	repoDir := filepath.Join(cfg.cacheDir, hex.EncodeToString(sum[:8]))
+	dir := filepath.Join(repoDir, sha)
+	unlockDir, err := lockCacheDir(ctx, repoDir)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if err != nil {
+			unlockDir()
+		}
+	}()
+	if _, err := os.Stat(dir); err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+		return dir, unlockDir, err
+	}
+
+	// Clone alongside the cached clones, so that an interrupted clone
+	// is never mistaken for a complete one.
+	if err := removeStaleClones(repoDir, time.Now()); err != nil {
+		return "", nil, err
+	}
+	tmpDir, err := ioutil.TempDir(repoDir, ".clone")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := fetch(tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", nil, err
+	}
+
+	// Clones of earlier commits won't be used again.
+	infos, err := ioutil.ReadDir(repoDir)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") {
+			if err := os.RemoveAll(filepath.Join(repoDir, info.Name())); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		// Another run sharing the cache may have cloned the same
+		// commit first.
+		os.RemoveAll(tmpDir)
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return "", nil, err
+		}
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	return dir, unlockDir, err
+}
+
+// removeStaleClones removes the temporary clones in repoDir which are
+// older than staleCloneAge at now.
+func removeStaleClones(repoDir string, now time.Time) error {
+	infos, err := ioutil.ReadDir(repoDir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".clone") || now.Sub(info.ModTime()) < staleCloneAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(repoDir, info.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remoteCommit returns the commit branch of the remote repository at url
+// points at, or its HEAD if branch is empty.
+func remoteCommit(ctx context.Context, cfg *config, url, branch string) (string, error) {
+	ref := "HEAD"
+	if branch != "" {
+		ref = "refs/heads/" + branch
+	}
+	cmd := commandContext(ctx, "git", "ls-remote", url, ref)
+	cmd.Env = cfg.gitEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(bytes.TrimSpace(out)))
+	if len(fields) < 2 {
+		return "", fmt.Errorf("%s not found", ref)
+	}
+	return fields[0], nil
+}
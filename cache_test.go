@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoveStaleClones(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		".clone1/go.mod": "module pack.ag/tftp\n",
+		".clone2/go.mod": "module pack.ag/tftp\n",
+		"abc123/go.mod":  "module pack.ag/tftp\n",
+	})
+	now := time.Now()
+	old := now.Add(-2 * staleCloneAge)
+	for _, name := range []string{".clone1", "abc123"} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := removeStaleClones(dir, now); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		".clone1": false, // left by an interrupted run
+		".clone2": true,  // possibly in progress
+		"abc123":  true,  // a complete clone
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %t, want %t", name, exists, want)
+		}
+	}
+}
+
+func TestCachedCloneConcurrent(t *testing.T) {
+	repo := t.TempDir()
+	gitInit(t, repo, map[string]string{"go.mod": "module pack.ag/tftp\n"})
+
+	cfg := testConfig("pack.ag")
+	cfg.cacheDir = t.TempDir()
+	var (
+		fetches int32
+		wg      sync.WaitGroup
+		dirs    = make([]string, 8)
+	)
+	fetch := func(dir string) error {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(10 * time.Millisecond)
+		return ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module pack.ag/tftp\n"), 0644)
+	}
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dir, unlock, err := cachedClone(context.Background(), cfg, repo, "", "", fetch)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			unlock()
+			dirs[i] = dir
+		}(i)
+	}
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+	for _, dir := range dirs[1:] {
+		if dir != dirs[0] {
+			t.Errorf("clones in %s and %s, want the same", dirs[0], dir)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dirs[0], "go.mod")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCachedCloneLocked(t *testing.T) {
+	repo := t.TempDir()
+	gitInit(t, repo, map[string]string{"go.mod": "module pack.ag/tftp\n"})
+	fetch := func(dir string) error {
+		return ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module pack.ag/tftp\n"), 0644)
+	}
+
+	tests := []struct {
+		name    string
+		age     time.Duration // of the lock held by another run
+		wantErr error
+	}{
+		{name: "held", wantErr: context.DeadlineExceeded},
+		{name: "stale", age: 2 * staleCloneAge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.cacheDir = t.TempDir()
+
+			// Find the repository's directory of the cache, then lock
+			// it as another run would.
+			dir, unlock, err := cachedClone(context.Background(), cfg, repo, "", "", fetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unlock()
+			lockFile := filepath.Join(filepath.Dir(dir), cacheLockFile)
+			if err := ioutil.WriteFile(lockFile, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+			old := time.Now().Add(-tt.age)
+			if err := os.Chtimes(lockFile, old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+			defer cancel()
+			_, unlock, err = cachedClone(ctx, cfg, repo, "", "", fetch)
+			if err != tt.wantErr {
+				t.Fatalf("cachedClone() = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			unlock()
+			if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+				t.Errorf("lock file left after unlocking: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkClean fails if cfg.out contains files but no generated pages,
+// since it may not be an output directory at all. It's checked before
+// generating so that nothing is written to the wrong directory.
+func checkClean(cfg *config) error {
+	infos, err := ioutil.ReadDir(cfg.out)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pages, err := orphanPages(cfg.out, nil)
+	if err != nil || len(pages) > 0 {
+		return err
+	}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") {
+			return fmt.Errorf("refusing to clean %s, it doesn't contain any generated pages", cfg.out)
+		}
+	}
+	return nil
+}
+
+// clean deletes the pages previously generated in the output directory
+// which weren't written by this run, as prune does, and the CNAME files
+// of its sites unless they were written, recording each deletion.
+// Dotfiles, such as .git, and other files which weren't generated are
+// kept.
+func (w *siteWriter) clean(cfg *config) error {
+	before := len(w.changes)
+	if err := w.prune(); err != nil {
+		return err
+	}
+
+	cnames := []string{"CNAME"}
+	if len(cfg.prefixes) > 1 {
+		cnames = cnames[:0]
+		for _, prefix := range cfg.prefixes {
+			cnames = append(cnames, prefix+"/CNAME")
+		}
+	}
+	for _, cname := range cnames {
+		if _, ok := w.hashes[cname]; ok {
+			continue
+		}
+		path := filepath.Join(w.dir, filepath.FromSlash(cname))
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		w.record(path, changeDeleted)
+	}
+
+	logf("Cleaned %d files from %s\n", len(w.changes)-before, w.dir)
+	return nil
+}
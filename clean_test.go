@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		files    map[string]string
+		written  []string
+		want     []fileChange
+	}{
+		{
+			name:     "single prefix",
+			prefixes: []string{"pack.ag"},
+			files: map[string]string{
+				"a.html":          testPage,
+				"stale.html":      testPage,
+				"CNAME":           "pack.ag\n",
+				"byhand.html":     "<html></html>",
+				".github/a.html":  testPage,
+				".well-known/x":   "",
+				"sub/.hidden.txt": "",
+			},
+			written: []string{"a.html"},
+			want: []fileChange{
+				{path: "stale.html", kind: changeDeleted},
+				{path: "CNAME", kind: changeDeleted},
+			},
+		},
+		{
+			name:     "written CNAME",
+			prefixes: []string{"pack.ag"},
+			files: map[string]string{
+				"a.html": testPage,
+				"CNAME":  "pack.ag\n",
+			},
+			written: []string{"a.html", "CNAME"},
+		},
+		{
+			name:     "several prefixes",
+			prefixes: []string{"pack.ag", "example.com"},
+			files: map[string]string{
+				"pack.ag/a.html":     testPage,
+				"pack.ag/CNAME":      "pack.ag\n",
+				"example.com/b.html": testPage,
+				"example.com/CNAME":  "example.com\n",
+			},
+			written: []string{"pack.ag/a.html", "pack.ag/CNAME"},
+			want: []fileChange{
+				{path: "example.com/b.html", kind: changeDeleted},
+				{path: "example.com/CNAME", kind: changeDeleted},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.files)
+
+			w := &siteWriter{dir: dir}
+			for _, p := range tt.written {
+				w.hash(filepath.Join(dir, filepath.FromSlash(p)), nil)
+			}
+			if err := w.clean(&config{prefixes: tt.prefixes}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(w.changes, tt.want) {
+				t.Errorf("changes = %+v, want %+v", w.changes, tt.want)
+			}
+
+			var remaining []string
+			for name := range tt.files {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					remaining = append(remaining, name)
+				}
+			}
+			sort.Strings(remaining)
+			var want []string
+			for name := range tt.files {
+				deleted := false
+				for _, c := range tt.want {
+					deleted = deleted || c.path == name
+				}
+				if !deleted {
+					want = append(want, name)
+				}
+			}
+			sort.Strings(want)
+			if !reflect.DeepEqual(remaining, want) {
+				t.Errorf("remaining files = %q, want %q", remaining, want)
+			}
+		})
+	}
+}
+
+func TestCheckClean(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "dotfiles", files: map[string]string{".git/HEAD": "", ".gitignore": ""}},
+		{name: "generated", files: map[string]string{"a.html": testPage, "README.md": ""}},
+		{name: "not generated", files: map[string]string{"README.md": "", "index.html": "<html></html>"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.files)
+			err := checkClean(&config{out: dir})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkClean() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
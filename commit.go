@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// defaultCommitMessage is the message of -git-commit commits when
+// -commit-message isn't set.
+const defaultCommitMessage = "Update vanity imports"
+
+// changeSummary counts the pages changed by a run, it's the data of the
+// -commit-message template.
+type changeSummary struct {
+	Added   int // pages of new imports
+	Removed int // pages deleted
+	Changed int // pages of existing imports which were modified
+	Files   int // all files changed, including the pages
+}
+
+// summarizeChanges counts the changes made to the pages of imports.
+func summarizeChanges(cfg *config, changes []fileChange, imports []vanityImport) changeSummary {
+	pages := make(map[string]bool, len(imports))
+	for _, imprt := range imports {
+		pages[filepath.ToSlash(cfg.pagePath(imprt, "."))] = true
+	}
+
+	s := changeSummary{Files: len(changes)}
+	for _, c := range changes {
+		switch {
+		case c.kind == changeDeleted && filepath.Ext(c.path) == ".html":
+			s.Removed++
+		case !pages[c.path]:
+		case c.kind == changeCreated:
+			s.Added++
+		case c.kind == changeModified:
+			s.Changed++
+		}
+	}
+	return s
+}
+
+// commitMessage renders the -commit-message template text with s.
+func commitMessage(text string, s changeSummary) (string, error) {
+	if text == "" {
+		text = defaultCommitMessage
+	}
+	t, err := template.New("commit").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// commitChanges commits the files changed by a run to the git repository
+// containing cfg.out. Other changes in the repository aren't committed.
+func commitChanges(ctx context.Context, cfg *config, changes []fileChange, imports []vanityImport) error {
+	if len(changes) == 0 {
+		logf("No changes to commit\n")
+		return nil
+	}
+
+	repo, err := findGitRepo(ctx, cfg.out)
+	if err != nil {
+		return err
+	}
+	if repo == nil {
+		return fmt.Errorf("%s isn't in a git repository", cfg.out)
+	}
+
+	msg, err := commitMessage(cfg.commitMessage, summarizeChanges(cfg, changes, imports))
+	if err != nil {
+		return fmt.Errorf("rendering commit message: %v", err)
+	}
+
+	// git runs in the top level of the working tree, rather than the
+	// directory cfg.out is relative to.
+	paths := make([]string, len(changes))
+	for i, c := range changes {
+		if paths[i], err = repo.relPath(filepath.Join(cfg.out, filepath.FromSlash(c.path))); err != nil {
+			return err
+		}
+	}
+
+	git := func(args ...string) error {
+		cmd := commandContext(ctx, "git", args...)
+		cmd.Dir = repo.dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if err := git(append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if err := git(append([]string{"commit", "--quiet", "-m", msg, "--"}, paths...)...); err != nil {
+		return err
+	}
+
+	logf("Committed %d changed files\n", len(changes))
+	return nil
+}
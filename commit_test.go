@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitMessage(t *testing.T) {
+	summary := changeSummary{Added: 3, Removed: 1, Changed: 2, Files: 7}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "", want: defaultCommitMessage},
+		{text: "Regenerate pages", want: "Regenerate pages"},
+		{text: "vanity: +{{.Added}} -{{.Removed}} packages", want: "vanity: +3 -1 packages"},
+		{text: "{{.Changed}} changed, {{.Files}} files", want: "2 changed, 7 files"},
+		{text: "{{.Missing}}", wantErr: true},
+		{text: "{{", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := commitMessage(tt.text, summary)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("commitMessage(%q) error = %v, want error %t", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("commitMessage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	cfg := &config{prefix: "pack.ag", prefixes: []string{"pack.ag"}}
+	imports := []vanityImport{
+		{Import: "pack.ag/a"},
+		{Import: "pack.ag/b"},
+		{Import: "pack.ag/c/d"},
+	}
+	changes := []fileChange{
+		{path: "a.html", kind: changeCreated},
+		{path: "b.html", kind: changeModified},
+		{path: "c/d.html", kind: changeCreated},
+		{path: "old.html", kind: changeDeleted},
+		{path: "index.html", kind: changeModified},
+		{path: "CNAME", kind: changeCreated},
+	}
+	want := changeSummary{Added: 2, Removed: 1, Changed: 1, Files: 6}
+	if got := summarizeChanges(cfg, changes, imports); got != want {
+		t.Errorf("summarizeChanges() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCommitChanges(t *testing.T) {
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"site/old.html": testPage,
+		"sub/README":    "",
+		"unrelated":     "",
+	})
+	writeTestFiles(t, dir, map[string]string{
+		"site/a.html": testPage,
+		"unrelated":   "changed by hand",
+	})
+
+	// -out is relative to the working directory, which isn't the top
+	// level of the working tree.
+	t.Chdir(filepath.Join(dir, "sub"))
+	cfg := &config{out: "../site", prefix: "pack.ag", prefixes: []string{"pack.ag"}, commitMessage: "vanity: +{{.Added}} packages"}
+	changes := []fileChange{{path: "a.html", kind: changeCreated}}
+	if err := commitChanges(context.Background(), cfg, changes, []vanityImport{{Import: "pack.ag/a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "show", "--name-only", "--format=%s").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(out)), "vanity: +1 packages\n\nsite/a.html"; got != want {
+		t.Errorf("committed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// diagnostics collects what's needed to troubleshoot a failed run for the
+// -diag-on-error bundle. Methods of a nil *diagnostics do nothing.
+type diagnostics struct {
+	log     bytes.Buffer // everything logged, guarded by logMu
+	results []repoResult
+	imports []vanityImport
+}
+
+// diag collects the diagnostics of the run when -diag-on-error is set.
+var diag *diagnostics
+
+// addResults records the repositories scanned and their outcome.
+func (d *diagnostics) addResults(results []repoResult) {
+	if d == nil {
+		return
+	}
+	d.results = append(d.results, results...)
+}
+
+// setImports records the imports pages are generated for.
+func (d *diagnostics) setImports(imports []vanityImport) {
+	if d == nil {
+		return
+	}
+	d.imports = imports
+}
+
+// writeDiagnostics writes a zip of the diagnostics of a run which failed
+// with runErr to path. Secrets are redacted from every file.
+func writeDiagnostics(path string, cfg *config, d *diagnostics, runErr error) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name, contents string) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(redact(contents)))
+		return err
+	}
+
+	var flags strings.Builder
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(&flags, "-%s=%s\n", f.Name, f.Value)
+	})
+	var env strings.Builder
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GOVANITY_") {
+			fmt.Fprintln(&env, kv)
+		}
+	}
+
+	var repos strings.Builder
+	for _, result := range d.results {
+		if result.err != nil {
+			fmt.Fprintf(&repos, "%s error: %v\n", result.url, result.err)
+			continue
+		}
+		fmt.Fprintf(&repos, "%s %d packages\n", result.url, len(result.imports))
+	}
+
+	// The partial results are in the format of -mappings, so the
+	// failure can be reproduced without searching.
+	imports := d.imports
+	if imports == nil {
+		for _, result := range d.results {
+			imports = append(imports, result.imports...)
+		}
+	}
+	var mappings strings.Builder
+	for _, imprt := range imports {
+		fmt.Fprintf(&mappings, "%s %s %s\n", imprt.Import, imprt.RepoURL, imprt.Branch)
+	}
+
+	logMu.Lock()
+	log := d.log.String()
+	logMu.Unlock()
+
+	files := []struct{ name, contents string }{
+		{"error.txt", runErr.Error() + "\n"},
+		{"flags.txt", flags.String()},
+		{"env.txt", env.String()},
+		{"versions.txt", versions()},
+		{"repos.txt", repos.String()},
+		{"mappings.txt", mappings.String()},
+		{"log.txt", log},
+	}
+	if cfg.configFile != "" {
+		data, err := ioutil.ReadFile(cfg.configFile)
+		if err != nil {
+			data = []byte(err.Error())
+		}
+		files = append(files, struct{ name, contents string }{"config.json", string(data)})
+	}
+	for _, f := range files {
+		if err := add(f.name, f.contents); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// versions returns the versions of govanity's runtime and of the go and
+// git commands it runs.
+func versions() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "govanity built with %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, args := range [][]string{{"go", "version"}, {"git", "--version"}} {
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", args[0], err)
+			continue
+		}
+		b.Write(out)
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	const token = "s3cret-token"
+	defer func(s []string) { secrets = s }(secrets)
+	addSecret(token)
+	defer func(d *diagnostics) { diag = d }(diag)
+	diag = new(diagnostics)
+	t.Setenv("GOVANITY_GITHUB_TOKEN", token)
+
+	dir := t.TempDir()
+	cfg := testConfig("pack.ag")
+	cfg.configFile = filepath.Join(dir, "govanity.json")
+	writeTestFiles(t, dir, map[string]string{
+		"govanity.json": `{"prefix": "pack.ag", "github_token": "` + token + `"}`,
+	})
+
+	// A run where one of two repositories failed to clone.
+	warnf("https://github.com/vcabbage/amqp", "git clone: fatal: could not read from https://%s@github.com", token)
+	diag.addResults([]repoResult{
+		{
+			url:     "https://github.com/vcabbage/tftp",
+			imports: []vanityImport{{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"}},
+		},
+		{url: "https://github.com/vcabbage/amqp", err: errors.New("git clone: exit status 128")},
+	})
+	path := filepath.Join(dir, "diag.zip")
+	if err := writeDiagnostics(path, cfg, diag, errors.New("aborting after 1 failed repositories: https://github.com/vcabbage/amqp")); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "error.txt", want: "aborting after 1 failed repositories: https://github.com/vcabbage/amqp\n"},
+		{name: "env.txt", want: "GOVANITY_GITHUB_TOKEN=***\n"},
+		{name: "versions.txt", want: "govanity built with go"},
+		{name: "repos.txt", want: "https://github.com/vcabbage/tftp 1 packages\nhttps://github.com/vcabbage/amqp error: git clone: exit status 128\n"},
+		{name: "mappings.txt", want: "pack.ag/tftp https://github.com/vcabbage/tftp master\n"},
+		{name: "log.txt", want: "WARNING: https://github.com/vcabbage/amqp: git clone: fatal: could not read from https://***@github.com\n"},
+		{name: "config.json", want: `"github_token": "***"`},
+	}
+	for _, tt := range tests {
+		got, ok := files[tt.name]
+		if !ok {
+			t.Errorf("%s is missing from the bundle", tt.name)
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+	if _, ok := files["flags.txt"]; !ok {
+		t.Error("flags.txt is missing from the bundle")
+	}
+	for name, contents := range files {
+		if strings.Contains(contents, token) {
+			t.Errorf("%s contains the token: %q", name, contents)
+		}
+	}
+}
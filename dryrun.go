@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// dryRun prints the path of each page which would be written for imports
+// and the import prefix of its go-import tag, without writing anything.
+// Pages which more than one import would be written to are reported, and
+// it fails if there are no imports.
+func dryRun(cfg *config, imports []vanityImport) error {
+	if len(imports) == 0 {
+		return errors.New("no packages found")
+	}
+
+	pages := append(imports[:len(imports):len(imports)], cfg.branchVariants(imports)...)
+	written := make(map[string]string, len(pages))
+	duplicates := 0
+	for _, imprt := range pages {
+		htmlPath := cfg.pagePath(imprt, cfg.out)
+		if other, ok := written[htmlPath]; ok {
+			warnf("", "%s: written for both %s and %s", htmlPath, other, imprt.Import)
+			duplicates++
+			continue
+		}
+		written[htmlPath] = imprt.Import
+		fmt.Printf("%s %s\n", htmlPath, imprt.ImportPrefix())
+	}
+
+	if duplicates > 0 {
+		return fmt.Errorf("%d pages would be written for more than one import", duplicates)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// parseExclude returns the comma separated glob patterns of s, checking
+// that each is valid.
+func parseExclude(s string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q: %v", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// excluded reports whether name matches any of the -exclude patterns.
+func (cfg *config) excluded(name string) bool {
+	for _, pattern := range cfg.excludes {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeRepos returns repoURLs without the repositories whose path,
+// such as owner/repo, matches an -exclude pattern.
+func excludeRepos(cfg *config, repoURLs []string) []string {
+	if len(cfg.excludes) == 0 {
+		return repoURLs
+	}
+
+	var kept []string
+	for _, repoURL := range repoURLs {
+		if cfg.excluded(repoPath(repoURL)) {
+			debugf("%s: excluded, skipping\n", repoURL)
+			continue
+		}
+		kept = append(kept, repoURL)
+	}
+	return kept
+}
+
+// repoPath returns the path of the repository at repoURL without its
+// host, such as owner/repo.
+func repoPath(repoURL string) string {
+	p := repoURL
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		p = u.Path
+	}
+	return strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+}
+
+// excludeImports returns imports without those whose import path matches
+// an -exclude pattern.
+func excludeImports(cfg *config, imports []vanityImport) []vanityImport {
+	if len(cfg.excludes) == 0 {
+		return imports
+	}
+
+	var kept []vanityImport
+	for _, imprt := range imports {
+		if cfg.excluded(imprt.Import) {
+			debugf("%s: excluded, skipping\n", imprt.Import)
+			continue
+		}
+		kept = append(kept, imprt)
+	}
+	return kept
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// readExpectations reads the imports expected to be generated from the
+// file at path, mapped to the expected repository URL, which may be empty.
+//
+// Each line contains an import path and optionally its repository URL,
+// separated by whitespace. Blank lines and lines beginning with # are
+// ignored.
+func readExpectations(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	expected := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected import path and optional repository URL", path, lineNum)
+		}
+		imprt := vanity.CleanImportPath(fields[0])
+		if len(fields) == 2 {
+			expected[imprt] = strings.TrimSuffix(fields[1], "/")
+		} else {
+			expected[imprt] = ""
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return expected, nil
+}
+
+// checkExpectations compares imports with expected, returning an error
+// listing the missing (-), unexpected (+), and mismatched (~) imports.
+func checkExpectations(imports []vanityImport, expected map[string]string) error {
+	found := make(map[string]string)
+	for _, imprt := range imports {
+		found[imprt.Import] = imprt.RepoURL
+	}
+
+	var diff []string
+	for imprt, repo := range expected {
+		foundRepo, ok := found[imprt]
+		switch {
+		case !ok:
+			diff = append(diff, "- "+imprt)
+		case repo != "" && repo != foundRepo:
+			diff = append(diff, fmt.Sprintf("~ %s: expected %s, found %s", imprt, repo, foundRepo))
+		}
+	}
+	for imprt := range found {
+		if _, ok := expected[imprt]; !ok {
+			diff = append(diff, "+ "+imprt)
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+
+	// Sort by import path rather than the kind of difference.
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i][2:] < diff[j][2:]
+	})
+	return fmt.Errorf("imports don't match expectations:\n\t%s", strings.Join(diff, "\n\t"))
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestCheckExpectations(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp"},
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp"},
+	}
+	tests := []struct {
+		name     string
+		expected map[string]string
+		want     string // empty if they match
+	}{
+		{
+			name: "match",
+			expected: map[string]string{
+				"pack.ag/tftp": "https://github.com/vcabbage/tftp",
+				"pack.ag/amqp": "",
+			},
+		},
+		{
+			name: "missing",
+			expected: map[string]string{
+				"pack.ag/tftp": "",
+				"pack.ag/amqp": "",
+				"pack.ag/sctp": "",
+			},
+			want: "imports don't match expectations:\n\t- pack.ag/sctp",
+		},
+		{
+			name: "extra",
+			expected: map[string]string{
+				"pack.ag/tftp": "",
+			},
+			want: "imports don't match expectations:\n\t+ pack.ag/amqp",
+		},
+		{
+			name: "mismatched repository",
+			expected: map[string]string{
+				"pack.ag/tftp": "https://github.com/vcabbage/go-tftp",
+				"pack.ag/amqp": "",
+			},
+			want: "imports don't match expectations:\n\t~ pack.ag/tftp: expected https://github.com/vcabbage/go-tftp, found https://github.com/vcabbage/tftp",
+		},
+		{
+			name: "sorted by import",
+			expected: map[string]string{
+				"pack.ag/tftp": "",
+				"pack.ag/sctp": "",
+			},
+			want: "imports don't match expectations:\n\t+ pack.ag/amqp\n\t- pack.ag/sctp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExpectations(imports, tt.expected)
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("checkExpectations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
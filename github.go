@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-github/github"
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// defaultGitHubURL is the URL of public GitHub, used unless -github-url
+// is set.
+const defaultGitHubURL = "https://github.com"
+
+// newGitHubClient returns a GitHub API client using client, which
+// talks to the API of the GitHub Enterprise instance at cfg.githubURL
+// if it's set.
+func newGitHubClient(cfg *config, client *http.Client) (*github.Client, error) {
+	gh := github.NewClient(client)
+	if cfg.githubURL == defaultGitHubURL {
+		return gh, nil
+	}
+
+	var err error
+	if gh.BaseURL, err = url.Parse(cfg.githubURL + "/api/v3/"); err != nil {
+		return nil, err
+	}
+	if gh.UploadURL, err = url.Parse(cfg.githubURL + "/api/uploads/"); err != nil {
+		return nil, err
+	}
+	return gh, nil
+}
+
+// githubCredentials returns the basic authentication credentials which
+// authenticate git to cfg.githubURL with cfg.githubToken, so private
+// repositories the token can list can also be cloned.
+func (cfg *config) githubCredentials() string {
+	return base64.StdEncoding.EncodeToString([]byte("x-access-token:" + cfg.githubToken))
+}
+
+// repository is a repository as returned by the GitHub API, see
+// vanity.Repository.
+type repository = vanity.Repository
+
+// repoLister is the part of the GitHub API used to find the repositories
+// to scan, so that it can be replaced, such as by a fake returning canned
+// repositories.
+type repoLister interface {
+	listRepositories(ctx context.Context, user string, opt *github.RepositoryListOptions) ([]*repository, *github.Response, error)
+	listLanguages(ctx context.Context, owner, repo string) (map[string]int, error)
+	getRepository(ctx context.Context, owner, repo string) (*repository, error)
+	searchRepositories(ctx context.Context, query string, opt *github.SearchOptions) ([]*repository, *github.Response, error)
+	listFiles(ctx context.Context, owner, repo, ref string) ([]string, error)
+}
+
+// githubLister is the repoLister backed by the GitHub API.
+type githubLister struct {
+	gh *github.Client
+}
+
+func (l githubLister) listRepositories(ctx context.Context, user string, opt *github.RepositoryListOptions) ([]*repository, *github.Response, error) {
+	return vanity.ListRepositories(ctx, l.gh, user, opt)
+}
+
+func (l githubLister) searchRepositories(ctx context.Context, query string, opt *github.SearchOptions) ([]*repository, *github.Response, error) {
+	return vanity.SearchRepositories(ctx, l.gh, query, opt)
+}
+
+func (l githubLister) getRepository(ctx context.Context, owner, repo string) (*repository, error) {
+	req, err := l.gh.NewRequest("GET", fmt.Sprintf("repos/%v/%v", url.PathEscape(owner), url.PathEscape(repo)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(repository)
+	if _, err := l.gh.Do(ctx, req, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// listFiles returns the paths of the files in the tree of ref.
+func (l githubLister) listFiles(ctx context.Context, owner, repo, ref string) ([]string, error) {
+	tree, _, err := l.gh.Git.GetTree(ctx, owner, repo, ref, true)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			files = append(files, entry.GetPath())
+		}
+	}
+	return files, nil
+}
+
+func (l githubLister) listLanguages(ctx context.Context, owner, repo string) (map[string]int, error) {
+	languages, _, err := l.gh.Repositories.ListLanguages(ctx, owner, repo)
+	return languages, err
+}
+
+// listAllRepositories returns every page of the repositories of user.
+func listAllRepositories(ctx context.Context, gh repoLister, cfg *config, user string) ([]*repository, error) {
+	opt := &github.RepositoryListOptions{
+		ListOptions: github.ListOptions{PerPage: cfg.perPage},
+	}
+	var all []*repository
+	for {
+		var (
+			repos []*repository
+			resp  *github.Response
+		)
+		err := cfg.retry.do(ctx, func() (err error) {
+			repos, resp, err = gh.listRepositories(ctx, user, opt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
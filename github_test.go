@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeLister is a repoLister returning canned repositories, languages,
+// and files, keyed by user, search query, or owner/repo. Missing
+// repositories are reported as not found.
+type fakeLister struct {
+	users     map[string][]*repository
+	searches  map[string][]*repository
+	languages map[string]map[string]int
+	files     map[string][]string
+
+	mu    sync.Mutex
+	calls map[string]int // by method
+}
+
+func (l *fakeLister) called(method string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.calls == nil {
+		l.calls = make(map[string]int)
+	}
+	l.calls[method]++
+}
+
+func (l *fakeLister) listRepositories(ctx context.Context, user string, opt *github.RepositoryListOptions) ([]*repository, *github.Response, error) {
+	l.called("listRepositories")
+	repos, ok := l.users[user]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	return repos, &github.Response{}, nil
+}
+
+func (l *fakeLister) searchRepositories(ctx context.Context, query string, opt *github.SearchOptions) ([]*repository, *github.Response, error) {
+	l.called("searchRepositories")
+	return l.searches[query], &github.Response{}, nil
+}
+
+func (l *fakeLister) getRepository(ctx context.Context, owner, repo string) (*repository, error) {
+	l.called("getRepository")
+	for _, r := range l.users[owner] {
+		if r.GetName() == repo {
+			return r, nil
+		}
+	}
+	return nil, notFound()
+}
+
+func (l *fakeLister) listLanguages(ctx context.Context, owner, repo string) (map[string]int, error) {
+	l.called("listLanguages")
+	languages, ok := l.languages[owner+"/"+repo]
+	if !ok {
+		return nil, notFound()
+	}
+	return languages, nil
+}
+
+func (l *fakeLister) listFiles(ctx context.Context, owner, repo, ref string) ([]string, error) {
+	l.called("listFiles")
+	files, ok := l.files[owner+"/"+repo]
+	if !ok {
+		return nil, notFound()
+	}
+	return files, nil
+}
+
+// notFound returns the error of a GitHub API request for something which
+// doesn't exist.
+func notFound() error {
+	return &github.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{Method: "GET"}},
+		Message:  "Not Found",
+	}
+}
+
+// testRepo returns a GitHub repository of owner named name.
+func testRepo(owner, name string, fork, archived bool) *repository {
+	return &repository{
+		Repository: github.Repository{
+			Name:          github.String(name),
+			FullName:      github.String(owner + "/" + name),
+			Owner:         &github.User{Login: github.String(owner)},
+			Fork:          github.Bool(fork),
+			CloneURL:      github.String(fmt.Sprintf("https://github.com/%s/%s.git", owner, name)),
+			HTMLURL:       github.String(fmt.Sprintf("https://github.com/%s/%s", owner, name)),
+			SVNURL:        github.String(fmt.Sprintf("https://github.com/%s/%s", owner, name)),
+			DefaultBranch: github.String("master"),
+		},
+		Archived: github.Bool(archived),
+	}
+}
+
+func TestGetPotentialRepos(t *testing.T) {
+	goRepo := testRepo("vcabbage", "tftp", false, false)
+	goRepo.Language = github.String("Go")
+	gh := &fakeLister{
+		users: map[string][]*repository{
+			"vcabbage": {
+				goRepo,
+				testRepo("vcabbage", "amqp", false, false),
+				testRepo("vcabbage", "fork", true, false),
+				testRepo("vcabbage", "site", false, false),
+				testRepo("vcabbage", "listed", false, false),
+			},
+		},
+		languages: map[string]map[string]int{
+			"vcabbage/amqp":   {"Go": 100, "Shell": 1},
+			"vcabbage/site":   {"JavaScript": 100},
+			"vcabbage/listed": {"Go": 100},
+			"other/go":        {"Go": 100},
+			"other/js":        {"JavaScript": 100},
+		},
+	}
+
+	cfg := testConfig("pack.ag")
+	cfg.githubURL = defaultGitHubURL
+	cfg.perPage = 100
+	cfg.searchList = []string{"other/go", "other/js", "vcabbage/listed", "vcabbage"}
+	got, err := getPotentialRepos(context.Background(), gh, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://github.com/other/go",
+		"https://github.com/vcabbage/listed",
+		"https://github.com/vcabbage/tftp",
+		"https://github.com/vcabbage/amqp",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repos = %q, want %q", got, want)
+	}
+	branch, err := cfg.defaultBranches.get("https://github.com/vcabbage/amqp", func() (string, error) {
+		return "", errors.New("listed default branch wasn't recorded")
+	})
+	if err != nil || branch != "master" {
+		t.Errorf("default branch = %q, %v, want master", branch, err)
+	}
+}
+
+func TestGetPotentialReposNotGo(t *testing.T) {
+	gh := &fakeLister{
+		languages: map[string]map[string]int{
+			"vcabbage/tftp": {"Go": 100},
+			"vcabbage/site": {"HTML": 90, "CSS": 10},
+			"vcabbage/docs": {},
+		},
+	}
+	tests := []struct {
+		search []string
+		strict bool
+		want   []string
+		err    string
+	}{
+		{
+			search: []string{"vcabbage/tftp", "vcabbage/site", "vcabbage/docs"},
+			want:   []string{"https://github.com/vcabbage/tftp"},
+		},
+		{
+			search: []string{"vcabbage/tftp", "vcabbage/site"},
+			strict: true,
+			err:    "vcabbage/site: explicitly listed but not a Go repository",
+		},
+		{
+			// Repositories whose languages can't be checked are
+			// still scanned.
+			search: []string{"vcabbage/missing"},
+			strict: true,
+			want:   []string{"https://github.com/vcabbage/missing"},
+		},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.githubURL = defaultGitHubURL
+		cfg.searchList = tt.search
+		cfg.strict = tt.strict
+		got, err := getPotentialRepos(context.Background(), gh, cfg)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("getPotentialRepos(%q) = %v, want %s", tt.search, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getPotentialRepos(%q) = %q, want %q", tt.search, got, tt.want)
+		}
+	}
+}
+
+func TestGetPotentialReposTemplates(t *testing.T) {
+	var repos []*repository
+	for _, name := range []string{"tftp", "template"} {
+		repo := testRepo("vcabbage", name, false, false)
+		repo.Language = github.String("Go")
+		repos = append(repos, repo)
+	}
+	repos[1].IsTemplate = github.Bool(true)
+	gh := &fakeLister{
+		users:    map[string][]*repository{"vcabbage": repos},
+		searches: map[string][]*repository{"topic:vanity": repos},
+	}
+
+	tests := []struct {
+		search           string
+		includeTemplates bool
+		want             []string
+	}{
+		{search: "vcabbage", want: []string{"https://github.com/vcabbage/tftp"}},
+		{search: "vcabbage", includeTemplates: true, want: []string{"https://github.com/vcabbage/tftp", "https://github.com/vcabbage/template"}},
+		{search: "topic:vanity", want: []string{"https://github.com/vcabbage/tftp"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.githubURL = defaultGitHubURL
+		cfg.searchList = []string{tt.search}
+		cfg.includeTemplates = tt.includeTemplates
+		got, err := getPotentialRepos(context.Background(), gh, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s, include templates %t: repos = %q, want %q", tt.search, tt.includeTemplates, got, tt.want)
+		}
+	}
+}
+
+func TestScanReposMaxFailures(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	base := t.TempDir()
+	gitInit(t, filepath.Join(base, "vcabbage", "tftp"), map[string]string{
+		"go.mod":  "module pack.ag/tftp\n",
+		"tftp.go": "package tftp\n",
+	})
+
+	// Only tftp exists to be cloned.
+	var repos []*repository
+	for _, name := range []string{"tftp", "amqp", "sctp"} {
+		repo := testRepo("vcabbage", name, false, false)
+		repo.Language = github.String("Go")
+		repo.SVNURL = github.String(base + "/vcabbage/" + name)
+		repos = append(repos, repo)
+	}
+	gh := &fakeLister{users: map[string][]*repository{"vcabbage": repos}}
+
+	tests := []struct {
+		maxFailures int
+		wantErr     bool
+	}{
+		{maxFailures: -1},
+		{maxFailures: 2},
+		{maxFailures: 1, wantErr: true},
+		{maxFailures: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.githubURL = defaultGitHubURL
+		cfg.searchList = []string{"vcabbage"}
+		cfg.moduleOnly = true
+		cfg.concurrency = 1
+		cfg.maxFailures = tt.maxFailures
+
+		repoURLs, err := getPotentialRepos(context.Background(), gh, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := scanRepos(context.Background(), nil, cfg, repoURLs)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("max failures %d: scanRepos() = %v, want error %t", tt.maxFailures, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		found := 0
+		for _, result := range results {
+			found += len(result.imports)
+		}
+		if found != 1 {
+			t.Errorf("max failures %d: found %d imports, want 1", tt.maxFailures, found)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// gitlabPrefix marks entries of the search list which refer to GitLab.
+const gitlabPrefix = "gitlab:"
+
+// gitlabProject is a project as returned by the GitLab API.
+type gitlabProject struct {
+	PathWithNamespace string    `json:"path_with_namespace"`
+	WebURL            string    `json:"web_url"`
+	Archived          bool      `json:"archived"`
+	ForkedFrom        *struct{} `json:"forked_from_project"`
+}
+
+// gitlabError is an unsuccessful response from the GitLab API.
+type gitlabError struct {
+	status int
+	msg    string
+}
+
+func (e *gitlabError) Error() string {
+	return "GitLab API: " + e.msg
+}
+
+// isGitLab reports whether the repository at repoURL is hosted on GitLab,
+// either gitlab.com or the instance at cfg.gitlabURL.
+func (cfg *config) isGitLab(repoURL string) bool {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return false
+	}
+	return u.Host == "gitlab.com" || u.Host == cfg.gitlabHost
+}
+
+// gitlabGet decodes the response of the GitLab API at path into v,
+// returning the next page from the X-Next-Page header, if any.
+func gitlabGet(ctx context.Context, cfg *config, path string, v interface{}) (next string, _ error) {
+	req, err := http.NewRequest("GET", cfg.gitlabURL+"/api/v4/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if cfg.gitlabToken != "" {
+		req.Header.Set("PRIVATE-TOKEN", cfg.gitlabToken)
+	}
+
+	err = cfg.retry.do(ctx, func() error {
+		resp, err := cfg.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			var body struct {
+				Message interface{} `json:"message"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			msg := fmt.Sprint(body.Message)
+			if body.Message == nil {
+				msg = resp.Status
+			}
+			return &gitlabError{status: resp.StatusCode, msg: msg}
+		}
+		next = resp.Header.Get("X-Next-Page")
+		return json.NewDecoder(resp.Body).Decode(v)
+	})
+	return next, err
+}
+
+// isNotFound reports whether err is a 404 from the GitLab API.
+func isNotFound(err error) bool {
+	e, ok := err.(*gitlabError)
+	return ok && e.status == http.StatusNotFound
+}
+
+// gitlabRepos returns the URLs of the Go repositories referred to by a
+// search list entry, without the gitlab: prefix. The entry is a project
+// path, or a group, whose projects in subgroups are included, or a user.
+// Forks and archived projects are left out as they are on GitHub.
+func gitlabRepos(ctx context.Context, cfg *config, entry string) ([]string, error) {
+	var project gitlabProject
+	_, err := gitlabGet(ctx, cfg, "projects/"+url.PathEscape(entry), &project)
+	switch {
+	case err == nil:
+		if project.Archived && cfg.excludeArchived {
+			debugf("%s%s: is archived\n", gitlabPrefix, entry)
+			return nil, nil
+		}
+		isGo, err := gitlabIsGo(ctx, cfg, entry)
+		if err != nil {
+			warnf(gitlabPrefix+entry, "checking languages: %v", err)
+		} else if !isGo {
+			if cfg.strict {
+				return nil, fmt.Errorf("%s%s: explicitly listed but not a Go repository", gitlabPrefix, entry)
+			}
+			warnf(gitlabPrefix+entry, "explicitly listed but not a Go repository, skipping")
+			return nil, nil
+		}
+		return []string{project.WebURL}, nil
+	case !isNotFound(err):
+		return nil, err
+	}
+
+	projects, err := gitlabProjects(ctx, cfg, "groups/"+url.PathEscape(entry)+"/projects", url.Values{"include_subgroups": {"true"}})
+	if isNotFound(err) {
+		projects, err = gitlabProjects(ctx, cfg, "users/"+url.PathEscape(entry)+"/projects", url.Values{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var repoURLs []string
+	for _, p := range projects {
+		name := gitlabPrefix + p.PathWithNamespace
+		if p.ForkedFrom != nil && !cfg.includeForks {
+			debugf("%s: is a fork\n", name)
+			continue
+		}
+		if p.Archived && cfg.excludeArchived {
+			debugf("%s: is archived\n", name)
+			continue
+		}
+
+		isGo, err := gitlabIsGo(ctx, cfg, p.PathWithNamespace)
+		if err != nil {
+			warnf(name, "%v", err)
+			continue
+		}
+		if !isGo {
+			debugf("%s: not a Go repository\n", name)
+			continue
+		}
+		repoURLs = append(repoURLs, p.WebURL)
+	}
+	return repoURLs, nil
+}
+
+// gitlabProjects returns every page of the project listing at path.
+func gitlabProjects(ctx context.Context, cfg *config, path string, q url.Values) ([]gitlabProject, error) {
+	q.Set("per_page", fmt.Sprint(cfg.perPage))
+	var all []gitlabProject
+	page := "1"
+	for page != "" {
+		q.Set("page", page)
+		var projects []gitlabProject
+		next, err := gitlabGet(ctx, cfg, path+"?"+q.Encode(), &projects)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, projects...)
+		page = next
+	}
+	return all, nil
+}
+
+// gitlabIsGo reports whether GitLab detected Go in the project at path.
+func gitlabIsGo(ctx context.Context, cfg *config, path string) (bool, error) {
+	var languages map[string]float64
+	if _, err := gitlabGet(ctx, cfg, "projects/"+url.PathEscape(path)+"/languages", &languages); err != nil {
+		return false, err
+	}
+	_, ok := languages["Go"]
+	return ok, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeGitLab serves the GitLab API for the projects of the group vcabbage
+// and the project vcabbage/archived, all of which contain Go.
+func fakeGitLab(t *testing.T) *httptest.Server {
+	forked := &struct{}{}
+	projects := []gitlabProject{
+		{PathWithNamespace: "vcabbage/tftp", WebURL: "https://gitlab.com/vcabbage/tftp"},
+		{PathWithNamespace: "vcabbage/fork", WebURL: "https://gitlab.com/vcabbage/fork", ForkedFrom: forked},
+		{PathWithNamespace: "vcabbage/archived", WebURL: "https://gitlab.com/vcabbage/archived", Archived: true},
+	}
+
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, v interface{}) {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+	mux.HandleFunc("/api/v4/projects/vcabbage", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		reply(w, map[string]string{"message": "404 Project Not Found"})
+	})
+	mux.HandleFunc("/api/v4/projects/vcabbage/archived", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, projects[2])
+	})
+	mux.HandleFunc("/api/v4/groups/vcabbage/projects", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, projects)
+	})
+	for _, p := range projects {
+		mux.HandleFunc("/api/v4/projects/"+p.PathWithNamespace+"/languages", func(w http.ResponseWriter, r *http.Request) {
+			reply(w, map[string]float64{"Go": 100})
+		})
+	}
+	// Project paths are escaped, so unescape them before routing.
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawPath = ""
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func TestGitLabRepos(t *testing.T) {
+	srv := fakeGitLab(t)
+	defer srv.Close()
+
+	tests := []struct {
+		entry           string
+		includeForks    bool
+		excludeArchived bool
+		want            []string
+	}{
+		{
+			entry: "vcabbage",
+			want:  []string{"https://gitlab.com/vcabbage/tftp", "https://gitlab.com/vcabbage/archived"},
+		},
+		{
+			entry:        "vcabbage",
+			includeForks: true,
+			want:         []string{"https://gitlab.com/vcabbage/tftp", "https://gitlab.com/vcabbage/fork", "https://gitlab.com/vcabbage/archived"},
+		},
+		{
+			entry:           "vcabbage",
+			excludeArchived: true,
+			want:            []string{"https://gitlab.com/vcabbage/tftp"},
+		},
+		{
+			entry: "vcabbage/archived",
+			want:  []string{"https://gitlab.com/vcabbage/archived"},
+		},
+		{
+			entry:           "vcabbage/archived",
+			excludeArchived: true,
+		},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.gitlabURL = srv.URL
+		cfg.perPage = 100
+		cfg.includeForks = tt.includeForks
+		cfg.excludeArchived = tt.excludeArchived
+		got, err := gitlabRepos(context.Background(), cfg, tt.entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("gitlabRepos(%s, forks %t, archived %t) = %q, want %q", tt.entry, tt.includeForks, tt.excludeArchived, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Supported -headers-file formats.
+const (
+	headersNetlify    = "netlify"
+	headersCloudflare = "cloudflare"
+)
+
+// headers returns the contents of a _headers file setting Cache-Control for
+// the generated pages. Netlify gets a rule per page, Cloudflare Pages limits
+// the number of rules so a single rule covering the whole site is used.
+func headers(format, cacheControl, base string, imports []vanityImport) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case headersNetlify:
+		for _, imprt := range imports {
+			fmt.Fprintf(&buf, "%s\n  Cache-Control: %s\n", urlPath(imprt, base), cacheControl)
+		}
+	case headersCloudflare:
+		fmt.Fprintf(&buf, "/*\n  Cache-Control: %s\n", cacheControl)
+	default:
+		return nil, fmt.Errorf("unknown headers format %q", format)
+	}
+	return buf.Bytes(), nil
+}
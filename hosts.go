@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostConfig limits the scanning of repositories on a host, keyed by the
+// host name, such as github.com.
+type hostConfig struct {
+	// Concurrency is the number of repositories on the host scanned at
+	// the same time, -concurrency when zero.
+	Concurrency int `json:"concurrency"`
+
+	// Rate is the number of repository scans started per second, or
+	// unlimited when zero.
+	Rate float64 `json:"rate"`
+}
+
+// hostLimits coordinates the scanning of repositories so that each host
+// is limited independently of the others.
+type hostLimits struct {
+	cfg *config
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+// hostLimit is the state of the limits of a single host.
+type hostLimit struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next scan
+}
+
+// acquire waits until a repository at repoURL may be scanned under the
+// limits of its host, returning a function releasing it once the scan
+// is done.
+func (l *hostLimits) acquire(ctx context.Context, repoURL string) (release func(), _ error) {
+	h := l.host(repoHost(repoURL))
+	select {
+	case h.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-h.sem }
+	if err := ctx.Err(); err != nil {
+		// Both cases of the select may have been ready.
+		release()
+		return nil, err
+	}
+
+	if h.interval > 0 {
+		h.mu.Lock()
+		now := time.Now()
+		start := h.next
+		if start.Before(now) {
+			start = now
+		}
+		h.next = start.Add(h.interval)
+		h.mu.Unlock()
+
+		t := time.NewTimer(start.Sub(now))
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// host returns the limits of host, creating them from the configuration
+// the first time.
+func (l *hostLimits) host(host string) *hostLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if h, ok := l.hosts[host]; ok {
+		return h
+	}
+
+	hc := l.cfg.hosts[host]
+	concurrency := hc.Concurrency
+	if concurrency == 0 {
+		concurrency = l.cfg.concurrency
+	}
+	h := &hostLimit{sem: make(chan struct{}, concurrency)}
+	if hc.Rate > 0 {
+		h.interval = time.Duration(float64(time.Second) / hc.Rate)
+	}
+
+	if l.hosts == nil {
+		l.hosts = make(map[string]*hostLimit)
+	}
+	l.hosts[host] = h
+	return h
+}
+
+// repoHost returns the host of repoURL, or "" if it isn't a URL, such as
+// a local path.
+func repoHost(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHostLimits(t *testing.T) {
+	cfg := testConfig("pack.ag")
+	cfg.concurrency = 2
+	cfg.hosts = map[string]hostConfig{
+		"slow.example": {Concurrency: 1},
+		"fast.example": {Concurrency: 3},
+	}
+	limits := &hostLimits{cfg: cfg}
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{host: "slow.example", want: 1},
+		{host: "fast.example", want: 3},
+		{host: "other.example", want: 2}, // -concurrency
+	}
+	for _, tt := range tests {
+		repoURL := "https://" + tt.host + "/vcabbage/tftp"
+
+		// Every host is filled up to its own limit while the others
+		// are still held, so one host never waits on another.
+		var releases []func()
+		for i := 0; i < tt.want; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			release, err := limits.acquire(ctx, repoURL)
+			cancel()
+			if err != nil {
+				t.Fatalf("%s: acquire %d: %v", tt.host, i+1, err)
+			}
+			releases = append(releases, release)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := limits.acquire(ctx, repoURL)
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Fatalf("%s: acquire %d = %v, want %v", tt.host, tt.want+1, err, context.DeadlineExceeded)
+		}
+
+		// Releasing one scan lets the next start.
+		releases[0]()
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+		release, err := limits.acquire(ctx, repoURL)
+		cancel()
+		if err != nil {
+			t.Fatalf("%s: acquire after release: %v", tt.host, err)
+		}
+		releases[0] = release
+		defer func() {
+			for _, release := range releases {
+				release()
+			}
+		}()
+	}
+}
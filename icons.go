@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// icons returns the URL paths the icons provided via -favicon and
+// -apple-touch-icon are served from. The icons are copied to the root of
+// out, so the paths are absolute to work from pages at any depth.
+func (cfg *config) icons() vanity.Icons {
+	var icons vanity.Icons
+	if cfg.favicon != "" {
+		icons.Favicon = cfg.iconPath(cfg.favicon)
+	}
+	if cfg.appleTouchIcon != "" {
+		icons.AppleTouchIcon = cfg.iconPath(cfg.appleTouchIcon)
+	}
+	return icons
+}
+
+// iconPath returns the URL path of the icon copied from file.
+func (cfg *config) iconPath(file string) string {
+	return path.Join("/", strings.TrimSuffix(cfg.prefixURL.Path, "/"), filepath.Base(file))
+}
+
+// writeIcons copies the icons provided via -favicon and -apple-touch-icon
+// to the root of the output directory.
+func writeIcons(w *siteWriter, cfg *config) error {
+	for _, file := range []string{cfg.favicon, cfg.appleTouchIcon} {
+		if file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := w.writeFile(filepath.Join(w.dir, filepath.Base(file)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestIconPath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		file   string
+		want   string
+	}{
+		{prefix: "pack.ag", file: "favicon.ico", want: "/favicon.ico"},
+		{prefix: "pack.ag", file: "assets/img/favicon.png", want: "/favicon.png"},
+		// Under a path prefix the icons are at the root of -out,
+		// which is served from the prefix.
+		{prefix: "example.com/go", file: "/tmp/favicon.ico", want: "/go/favicon.ico"},
+		{prefix: "example.com/go/", file: "favicon.ico", want: "/go/favicon.ico"},
+	}
+	for _, tt := range tests {
+		if got := testConfig(tt.prefix).iconPath(tt.file); got != tt.want {
+			t.Errorf("iconPath(%q) with prefix %s = %q, want %q", tt.file, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// packageLinks are the links to a package written to the -links-file.
+type packageLinks struct {
+	URL    string `json:"url"`
+	Docs   string `json:"docs"`
+	Source string `json:"source"`
+}
+
+// writeLinks writes a JSON object mapping each import path to links for
+// the package to path, such as for generating short links or QR codes.
+func writeLinks(path string, imports []vanityImport) error {
+	links := make(map[string]packageLinks, len(imports))
+	for _, imprt := range imports {
+		links[imprt.Import] = packageLinks{
+			URL:    "https://" + imprt.Import,
+			Docs:   "https://pkg.go.dev/" + imprt.Import,
+			Source: imprt.RepoURL,
+		}
+	}
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestListIssueLinks(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp"},
+		{Import: "pack.ag/lab", RepoURL: "https://gitlab.com/vcabbage/lab.git", GitLab: true},
+	}
+	tests := []struct {
+		issueLinks bool
+		want       []string
+	}{
+		{
+			want: []string{
+				`<li><a href="https://pack.ag/tftp">pack.ag/tftp</a> (<a href="https://github.com/vcabbage/tftp">source</a>)</li>`,
+				`<li><a href="https://pack.ag/lab">pack.ag/lab</a> (<a href="https://gitlab.com/vcabbage/lab.git">source</a>)</li>`,
+			},
+		},
+		{
+			issueLinks: true,
+			want: []string{
+				`(<a href="https://github.com/vcabbage/tftp">source</a>, <a href="https://github.com/vcabbage/tftp/issues">report an issue</a>)`,
+				`(<a href="https://gitlab.com/vcabbage/lab.git">source</a>, <a href="https://gitlab.com/vcabbage/lab/-/issues">report an issue</a>)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := listTmpl.Execute(&buf, importList{Title: "pack.ag", Imports: imports, IssueLinks: tt.issueLinks}); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("issue links %t: list doesn't contain %s:\n%s", tt.issueLinks, want, &buf)
+			}
+		}
+		if !tt.issueLinks && strings.Contains(buf.String(), "issues") {
+			t.Errorf("list links issues without issue links:\n%s", &buf)
+		}
+	}
+}
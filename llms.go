@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// llmsTxt returns an llms.txt (https://llmstxt.org) listing imports. It
+// has the prefix as the title, and a section with a link to each package,
+// followed by its synopsis and repository.
+func llmsTxt(prefix string, imports []vanityImport) []byte {
+	sorted := append([]vanityImport(nil), imports...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Import < sorted[j].Import
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", prefix)
+	fmt.Fprintf(&buf, "> Go packages available under the %s import path prefix.\n\n", prefix)
+	buf.WriteString("## Packages\n\n")
+	for _, imprt := range sorted {
+		notes := "Source: " + imprt.RepoURL
+		if imprt.Synopsis != "" {
+			notes = imprt.Synopsis + " " + notes
+		}
+		fmt.Fprintf(&buf, "- [%s](https://pkg.go.dev/%s): %s\n", imprt.Import, imprt.Import, notes)
+	}
+	return buf.Bytes()
+}
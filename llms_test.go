@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLLMSTxt(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Synopsis: "Package tftp provides TFTP client and server implementations."},
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Synopsis: "Package amqp provides an AMQP 1.0 client implementation."},
+		{Import: "pack.ag/tools", RepoURL: "https://github.com/vcabbage/tools"},
+	}
+	got := string(llmsTxt("pack.ag", imports))
+
+	want := "# pack.ag\n\n" +
+		"> Go packages available under the pack.ag import path prefix.\n\n" +
+		"## Packages\n\n" +
+		"- [pack.ag/amqp](https://pkg.go.dev/pack.ag/amqp): Package amqp provides an AMQP 1.0 client implementation. Source: https://github.com/vcabbage/amqp\n" +
+		"- [pack.ag/tftp](https://pkg.go.dev/pack.ag/tftp): Package tftp provides TFTP client and server implementations. Source: https://github.com/vcabbage/tftp\n" +
+		"- [pack.ag/tools](https://pkg.go.dev/pack.ag/tools): Source: https://github.com/vcabbage/tools\n"
+	if got != want {
+		t.Errorf("llms.txt =\n%s\nwant\n%s", got, want)
+	}
+	for _, imprt := range imports {
+		if imprt.Synopsis != "" && !strings.Contains(got, "("+"https://pkg.go.dev/"+imprt.Import+"): "+imprt.Synopsis) {
+			t.Errorf("%s isn't listed with its synopsis", imprt.Import)
+		}
+	}
+}
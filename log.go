@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported -log-format values.
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+var (
+	// logLevel is the least severe level of message printed, lowered
+	// by -v and raised by -quiet.
+	logLevel = slog.LevelInfo
+
+	// logFormat is how messages are printed, one of logText or logJSON.
+	logFormat = logText
+)
+
+// logf prints an informational message to stdout with secrets redacted.
+// All informational output should go through logf, or one of the
+// functions below, since messages may contain URLs or command output
+// which include credentials.
+func logf(format string, args ...interface{}) {
+	logAt(slog.LevelInfo, "", format, args...)
+}
+
+// debugf prints a message, such as why a repository or package was
+// skipped, which is only of interest with -v.
+func debugf(format string, args ...interface{}) {
+	logAt(slog.LevelDebug, "", format, args...)
+}
+
+// warnf prints a message about a problem which didn't stop the run,
+// concerning the repository repo if it isn't empty.
+func warnf(repo, format string, args ...interface{}) {
+	logAt(slog.LevelWarn, repo, format, args...)
+}
+
+// reportf prints output which was asked for, such as the -list-packages
+// report, regardless of -quiet or -log-format.
+func reportf(format string, args ...interface{}) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	msg := redact(fmt.Sprintf(format, args...))
+	if diag != nil {
+		diag.log.WriteString(msg)
+	}
+	printLog(msg)
+}
+
+// logAt prints a message at level if it's at least logLevel. Every
+// message is recorded for diagnostics.
+func logAt(level slog.Level, repo, format string, args ...interface{}) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	msg := redact(fmt.Sprintf(format, args...))
+	repo = redact(repo)
+	text := msg
+	if repo != "" {
+		text = repo + ": " + text
+	}
+	if level >= slog.LevelWarn {
+		text = "WARNING: " + text
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if diag != nil {
+		diag.log.WriteString(text)
+	}
+	if level < logLevel {
+		return
+	}
+
+	if logFormat == logJSON {
+		var buf bytes.Buffer
+		r := slog.NewRecord(time.Now(), level, strings.TrimSpace(msg), 0)
+		if repo != "" {
+			r.AddAttrs(slog.String("repo", repo))
+		}
+		if err := slog.NewJSONHandler(&buf, nil).Handle(context.Background(), r); err == nil {
+			text = buf.String()
+		}
+	}
+	printLog(text)
+}
+
+// printLog prints msg, keeping the progress bar below it if it's shown.
+// logMu must be held.
+func printLog(msg string) {
+	if bar == nil {
+		fmt.Print(msg)
+		return
+	}
+	// Print the message in place of the progress bar, and redraw
+	// the bar below it.
+	fmt.Print("\r\033[K" + msg)
+	bar.draw()
+}
+
+// logMu serializes output.
+var logMu sync.Mutex
@@ -1,39 +1,273 @@
 package main // import "pack.ag/cmd/govanity"
 
 import (
-	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io/ioutil"
-	"net/http"
+	"log/slog"
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	texttemplate "text/template"
+	"time"
 
 	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
+	"pack.ag/cmd/govanity/vanity"
 )
 
 func configuration() (config, error) {
-	cname := os.Getenv("GOVANITY_CNAME")
+	perPage, err := envInt("GOVANITY_PER_PAGE", 100)
+	if err != nil {
+		return config{}, err
+	}
+	maxFailures, err := envInt("GOVANITY_MAX_FAILURES", -1)
+	if err != nil {
+		return config{}, err
+	}
+	prefilter, err := envInt("GOVANITY_PREFILTER", 0)
+	if err != nil {
+		return config{}, err
+	}
+	concurrency, err := envInt("GOVANITY_CONCURRENCY", 4)
+	if err != nil {
+		return config{}, err
+	}
+	retryAttempts, err := envInt("GOVANITY_RETRY_ATTEMPTS", 3)
+	if err != nil {
+		return config{}, err
+	}
+	retryDelay, err := envDuration("GOVANITY_RETRY_DELAY", time.Second)
+	if err != nil {
+		return config{}, err
+	}
+	retryMaxDelay, err := envDuration("GOVANITY_RETRY_MAX_DELAY", 30*time.Second)
+	if err != nil {
+		return config{}, err
+	}
+	retryJitter, err := envFloat("GOVANITY_RETRY_JITTER", 0.2)
+	if err != nil {
+		return config{}, err
+	}
+	rateLimitWait, err := envDuration("GOVANITY_RATE_LIMIT_WAIT", 0)
+	if err != nil {
+		return config{}, err
+	}
+	cloneTimeout, err := envDuration("GOVANITY_CLONE_TIMEOUT", 0)
+	if err != nil {
+		return config{}, err
+	}
+	timeout, err := envDuration("GOVANITY_TIMEOUT", 0)
+	if err != nil {
+		return config{}, err
+	}
+
 	cfg := config{
-		prefix:      os.Getenv("GOVANITY_PREFIX"),
-		search:      os.Getenv("GOVANITY_SEARCH"),
-		out:         os.Getenv("GOVANITY_OUT"),
-		githubToken: os.Getenv("GOVANITY_GITHUB_TOKEN"),
-		writeCNAME:  cname != "" && cname != "0",
+		prefix:          os.Getenv("GOVANITY_PREFIX"),
+		search:          os.Getenv("GOVANITY_SEARCH"),
+		searchFile:      os.Getenv("GOVANITY_SEARCH_FILE"),
+		cacheDir:        os.Getenv("GOVANITY_CACHE_DIR"),
+		logFormat:       os.Getenv("GOVANITY_LOG_FORMAT"),
+		out:             os.Getenv("GOVANITY_OUT"),
+		githubToken:     os.Getenv("GOVANITY_GITHUB_TOKEN"),
+		githubURL:       os.Getenv("GOVANITY_GITHUB_URL"),
+		gitlabToken:     os.Getenv("GOVANITY_GITLAB_TOKEN"),
+		gitlabURL:       os.Getenv("GOVANITY_GITLAB_URL"),
+		resolverCommand: os.Getenv("GOVANITY_RESOLVER_COMMAND"),
+		configFile:      os.Getenv("GOVANITY_CONFIG"),
+		mappings:        os.Getenv("GOVANITY_MAPPINGS"),
+		modules:         os.Getenv("GOVANITY_MODULES"),
+		expect:          os.Getenv("GOVANITY_EXPECT"),
+		writeCNAME:      envBool("GOVANITY_CNAME"),
+		cgo:             envBool("GOVANITY_CGO"),
+		tags:            os.Getenv("GOVANITY_TAGS"),
+		goos:            os.Getenv("GOVANITY_GOOS"),
+		goarch:          os.Getenv("GOVANITY_GOARCH"),
+		openGraph:       envBool("GOVANITY_OPENGRAPH"),
+		issueLinks:      envBool("GOVANITY_ISSUE_LINKS"),
+		moduleOnly:      envBool("GOVANITY_MODULE_ONLY"),
+		caseInsensitive: envBool("GOVANITY_CASE_INSENSITIVE"),
+		perPage:         perPage,
+		maxFailures:     maxFailures,
+		prefilter:       prefilter,
+		concurrency:     concurrency,
+		cloneTimeout:    cloneTimeout,
+		timeout:         timeout,
+		retry: retryPolicy{
+			attempts:  retryAttempts,
+			baseDelay: retryDelay,
+			maxDelay:  retryMaxDelay,
+			jitter:    retryJitter,
+
+			rateLimitWait: rateLimitWait,
+		},
+
+		rootBehavior:   os.Getenv("GOVANITY_ROOT_BEHAVIOR"),
+		layout:         os.Getenv("GOVANITY_LAYOUT"),
+		rootRedirect:   os.Getenv("GOVANITY_ROOT_REDIRECT"),
+		patch:          os.Getenv("GOVANITY_PATCH"),
+		changedFiles:   os.Getenv("GOVANITY_CHANGED_FILES"),
+		metricsFile:    os.Getenv("GOVANITY_METRICS_FILE"),
+		linksFile:      os.Getenv("GOVANITY_LINKS_FILE"),
+		manifest:       os.Getenv("GOVANITY_MANIFEST"),
+		integrityFile:  os.Getenv("GOVANITY_INTEGRITY_FILE"),
+		insecureServe:  os.Getenv("GOVANITY_INSECURE_SERVE"),
+		commitMessage:  os.Getenv("GOVANITY_COMMIT_MESSAGE"),
+		diagOnError:    os.Getenv("GOVANITY_DIAG_ON_ERROR"),
+		postProcess:    os.Getenv("GOVANITY_POST_PROCESS_COMMAND"),
+		template:       os.Getenv("GOVANITY_TEMPLATE"),
+		redirect:       os.Getenv("GOVANITY_REDIRECT"),
+		sourceFormat:   os.Getenv("GOVANITY_GO_SOURCE_FORMAT"),
+		serve:          os.Getenv("GOVANITY_SERVE"),
+		headersFile:    os.Getenv("GOVANITY_HEADERS_FILE"),
+		robots:         os.Getenv("GOVANITY_ROBOTS"),
+		proxy:          os.Getenv("GOVANITY_PROXY"),
+		cacheControl:   os.Getenv("GOVANITY_CACHE_CONTROL"),
+		clonePattern:   os.Getenv("GOVANITY_CLONE_PATTERN"),
+		cloneProto:     os.Getenv("GOVANITY_CLONE_PROTO"),
+		matchPattern:   os.Getenv("GOVANITY_MATCH"),
+		exclude:        os.Getenv("GOVANITY_EXCLUDE"),
+		cloneReplace:   os.Getenv("GOVANITY_CLONE_REPLACE"),
+		favicon:        os.Getenv("GOVANITY_FAVICON"),
+		appleTouchIcon: os.Getenv("GOVANITY_APPLE_TOUCH_ICON"),
+
+		respectGitignore: envBool("GOVANITY_RESPECT_GITIGNORE"),
+		gitCommit:        envBool("GOVANITY_GIT_COMMIT"),
+		listPackages:     envBool("GOVANITY_LIST_PACKAGES"),
+		skipInternal:     envBool("GOVANITY_SKIP_INTERNAL"),
+		majorBranches:    envBool("GOVANITY_MAJOR_BRANCHES"),
+		commands:         envBoolDefault("GOVANITY_COMMANDS", true),
+		api:              envBool("GOVANITY_API"),
+		prune:            envBool("GOVANITY_PRUNE"),
+		clean:            envBool("GOVANITY_CLEAN"),
+		tarball:          envBool("GOVANITY_TARBALL"),
+		noCache:          envBool("GOVANITY_NO_CACHE"),
+		sparse:           envBool("GOVANITY_SPARSE"),
+		verifySource:     envBool("GOVANITY_VERIFY_SOURCE"),
+		normalize:        envBool("GOVANITY_NORMALIZE"),
+		includeTemplates: envBool("GOVANITY_INCLUDE_TEMPLATES"),
+		includeForks:     envBool("GOVANITY_INCLUDE_FORKS"),
+		excludeArchived:  envBool("GOVANITY_EXCLUDE_ARCHIVED"),
+		progress:         envBool("GOVANITY_PROGRESS"),
+		selfTest:         envBool("GOVANITY_SELFTEST"),
+		strict:           envBool("GOVANITY_STRICT"),
+		findOrphans:      envBool("GOVANITY_FIND_ORPHANS"),
+		dryRun:           envBool("GOVANITY_DRY_RUN"),
+		llmsTxt:          envBool("GOVANITY_LLMS_TXT"),
+		netlify:          envBool("GOVANITY_NETLIFY"),
+		netlifyMode:      os.Getenv("GOVANITY_NETLIFY_MODE"),
+		verbose:          envBool("GOVANITY_VERBOSE"),
+		failOnEmpty:      envBool("GOVANITY_FAIL_ON_EMPTY"),
+		quiet:            envBool("GOVANITY_QUIET"),
+
+		defaultBranches: new(branchCache),
 	}
 
-	flag.StringVar(&cfg.prefix, "prefix", cfg.prefix, "vanity URL prefix to match in import comments (required) [GOVANITY_PREFIX]")
+	flag.StringVar(&cfg.prefix, "prefix", cfg.prefix, "vanity URL prefix to match in import comments, or a comma separated list of prefixes whose sites are written to their own directories of -out (required) [GOVANITY_PREFIX]")
 	flag.StringVar(&cfg.search, "search", cfg.search, "comma seperated list of GitHub usernames/orgs/repos to search (required) [GOVANITY_SEARCH]")
+	flag.StringVar(&cfg.searchFile, "search-file", cfg.searchFile, "file of GitHub usernames/orgs/repos to search, one per line, in addition to search (optional) [GOVANITY_SEARCH_FILE]")
 	flag.StringVar(&cfg.out, "out", cfg.out, "base directory to write generated files to (required) [GOVANITY_OUT]")
 	flag.BoolVar(&cfg.writeCNAME, "cname", cfg.writeCNAME, "write CNAME file for GitHub Pages (default: false) [GOVANITY_CNAME]")
-	flag.StringVar(&cfg.githubToken, "token", cfg.githubToken, "GitHub API token to avoid rate limiting (optional) [GOVANITY_GITHUB_TOKEN]")
+	flag.StringVar(&cfg.githubToken, "token", cfg.githubToken, "GitHub API token to avoid rate limiting, also used to clone private repositories (optional) [GOVANITY_GITHUB_TOKEN]")
+	flag.StringVar(&cfg.gitlabToken, "gitlab-token", cfg.gitlabToken, "GitLab API token for searching gitlab: entries (optional) [GOVANITY_GITLAB_TOKEN]")
+	flag.StringVar(&cfg.sourceFormat, "go-source-format", cfg.sourceFormat, "template of the home, directory, and file URLs of go-source, separated by spaces, given the RepoURL, Branch, and Subdir such as {{.RepoURL}} {{.RepoURL}}/tree/{{.Branch}}{{.Subdir}}{/dir} {{.RepoURL}}/blob/{{.Branch}}{{.Subdir}}{/dir}/{file}#L{line} (optional) [GOVANITY_GO_SOURCE_FORMAT]")
+	flag.StringVar(&cfg.redirect, "redirect", cfg.redirect, "where visitors to a page are sent and the go-source home, one of repo, pkgsite, or a URL template given the import such as https://docs.example.com/{{.Import}} (default: repo) [GOVANITY_REDIRECT]")
+	flag.StringVar(&cfg.template, "template", cfg.template, "HTML template file used for the page of each import instead of the built-in template, given the same data (optional) [GOVANITY_TEMPLATE]")
+	flag.StringVar(&cfg.postProcess, "post-process-command", cfg.postProcess, "command each generated HTML page is piped through before it's written, such as a minifier (optional) [GOVANITY_POST_PROCESS_COMMAND]")
+	flag.StringVar(&cfg.resolverCommand, "resolver-command", cfg.resolverCommand, "command run with each resolver: search entry as its last argument, printing a JSON array of repositories with url and optionally web_url (optional) [GOVANITY_RESOLVER_COMMAND]")
+	flag.StringVar(&cfg.githubURL, "github-url", cfg.githubURL, "URL of the GitHub instance, such as a GitHub Enterprise server, that search entries other than gitlab: and resolver: are looked up on (default: https://github.com) [GOVANITY_GITHUB_URL]")
+	flag.StringVar(&cfg.gitlabURL, "gitlab-url", cfg.gitlabURL, "URL of the GitLab instance searched for gitlab: entries (default: https://gitlab.com) [GOVANITY_GITLAB_URL]")
+	flag.StringVar(&cfg.mappings, "mappings", cfg.mappings, "file of explicit import to repository mappings, replaces searching (optional) [GOVANITY_MAPPINGS]")
+	flag.StringVar(&cfg.expect, "expect", cfg.expect, "file of the import paths, and optionally repositories, expected to be generated, failing without writing files if they don't match (optional) [GOVANITY_EXPECT]")
+	flag.StringVar(&cfg.modules, "modules", cfg.modules, "file containing the output of go list -m all, only modules listed get pages, pinned to the listed version, - for stdin (optional) [GOVANITY_MODULES]")
+	flag.StringVar(&cfg.configFile, "config", cfg.configFile, "JSON configuration file (optional) [GOVANITY_CONFIG]")
+	flag.StringVar(&cfg.layout, "layout", cfg.layout, "how package pages are named, file for path/name.html or dir for path/name/index.html (default: file) [GOVANITY_LAYOUT]")
+	flag.StringVar(&cfg.rootBehavior, "root-behavior", cfg.rootBehavior, "what to write at the root index.html, one of index, redirect, none (default: none) [GOVANITY_ROOT_BEHAVIOR]")
+	flag.StringVar(&cfg.rootRedirect, "root-redirect", cfg.rootRedirect, "URL the root index.html redirects to when root-behavior is redirect [GOVANITY_ROOT_REDIRECT]")
+	flag.StringVar(&cfg.patch, "patch", cfg.patch, "write a diff of the changes to out to this file, - for stdout, instead of writing them (optional) [GOVANITY_PATCH]")
+	flag.StringVar(&cfg.changedFiles, "changed-files", cfg.changedFiles, "write the files created, modified, or deleted by this run to this file, - for stdout (optional) [GOVANITY_CHANGED_FILES]")
+	flag.StringVar(&cfg.serve, "serve", cfg.serve, "serve the import pages over HTTP at this address, rendering them for each request, instead of writing files (optional) [GOVANITY_SERVE]")
+	flag.StringVar(&cfg.insecureServe, "insecure-serve", cfg.insecureServe, "after writing files, serve out over plain HTTP at this address for local testing (optional) [GOVANITY_INSECURE_SERVE]")
+	flag.StringVar(&cfg.integrityFile, "integrity-file", cfg.integrityFile, "write the SHA-256 hash of every generated file to this file, in the format of sha256sum relative to out (optional) [GOVANITY_INTEGRITY_FILE]")
+	flag.StringVar(&cfg.manifest, "manifest", cfg.manifest, "write a JSON file describing the page generated for each import, including its import prefix, repository, and branch (optional) [GOVANITY_MANIFEST]")
+	flag.StringVar(&cfg.linksFile, "links-file", cfg.linksFile, "write a JSON file mapping each import path to its vanity, documentation, and source URLs (optional) [GOVANITY_LINKS_FILE]")
+	flag.StringVar(&cfg.diagOnError, "diag-on-error", cfg.diagOnError, "when the run fails, write a zip of diagnostics for bug reports to this file, including the flags, logs, and partial results with secrets redacted (optional) [GOVANITY_DIAG_ON_ERROR]")
+	flag.StringVar(&cfg.metricsFile, "metrics-file", cfg.metricsFile, "write Prometheus metrics about the run to this file, such as for the node exporter textfile collector (optional) [GOVANITY_METRICS_FILE]")
+	flag.StringVar(&cfg.proxy, "proxy", cfg.proxy, "HTTP proxy URL used for GitHub API requests and git, instead of the proxy environment variables (optional) [GOVANITY_PROXY]")
+	flag.StringVar(&cfg.robots, "robots", cfg.robots, "write a robots.txt allowing or disallowing all crawlers, one of allow, disallow (optional) [GOVANITY_ROBOTS]")
+	flag.StringVar(&cfg.headersFile, "headers-file", cfg.headersFile, "write a _headers file for Netlify or Cloudflare Pages, one of netlify, cloudflare (optional) [GOVANITY_HEADERS_FILE]")
+	flag.StringVar(&cfg.cacheControl, "cache-control", cfg.cacheControl, "Cache-Control value for the headers file (default: \"public, max-age=300\") [GOVANITY_CACHE_CONTROL]")
+	flag.StringVar(&cfg.exclude, "exclude", cfg.exclude, "comma separated glob patterns of repositories (owner/repo) and import paths to skip (optional) [GOVANITY_EXCLUDE]")
+	flag.StringVar(&cfg.matchPattern, "match", cfg.matchPattern, "regular expression the vanity import paths of packages must match, others are skipped before their imports are built (optional) [GOVANITY_MATCH]")
+	flag.StringVar(&cfg.cloneProto, "clone-proto", cfg.cloneProto, "protocol repositories are cloned over, https or ssh for git@host:owner/repo.git URLs authenticated by ssh, source links still use https (default: https) [GOVANITY_CLONE_PROTO]")
+	flag.StringVar(&cfg.clonePattern, "clone-pattern", cfg.clonePattern, "regular expression matching repository URLs to rewrite before cloning (optional) [GOVANITY_CLONE_PATTERN]")
+	flag.StringVar(&cfg.favicon, "favicon", cfg.favicon, "icon file copied to out and linked from generated pages (optional) [GOVANITY_FAVICON]")
+	flag.StringVar(&cfg.appleTouchIcon, "apple-touch-icon", cfg.appleTouchIcon, "apple-touch-icon file copied to out and linked from generated pages (optional) [GOVANITY_APPLE_TOUCH_ICON]")
+	flag.StringVar(&cfg.cloneReplace, "clone-replace", cfg.cloneReplace, "replacement for URLs matching clone-pattern, may reference groups as $1 (optional) [GOVANITY_CLONE_REPLACE]")
+	flag.IntVar(&cfg.perPage, "per-page", cfg.perPage, "number of repositories to request per GitHub API call, max 100 [GOVANITY_PER_PAGE]")
+	flag.IntVar(&cfg.concurrency, "concurrency", cfg.concurrency, "number of repositories on each host scanned at the same time, unless set for the host in the configuration file [GOVANITY_CONCURRENCY]")
+	flag.IntVar(&cfg.prefilter, "prefilter", cfg.prefilter, "fetch the go.mod of each GitHub repository with this many concurrent requests, skipping repositories none of whose modules match prefix before cloning with -module-only, 0 to disable [GOVANITY_PREFILTER]")
+	flag.IntVar(&cfg.maxFailures, "max-failures", cfg.maxFailures, "abort once more than this many repositories fail, -1 for unlimited [GOVANITY_MAX_FAILURES]")
+	flag.DurationVar(&cfg.cloneTimeout, "clone-timeout", cfg.cloneTimeout, "longest to spend fetching each repository, including retries, before skipping it, 0 for no limit [GOVANITY_CLONE_TIMEOUT]")
+	flag.DurationVar(&cfg.timeout, "timeout", cfg.timeout, "deadline for the whole run, 0 for no limit [GOVANITY_TIMEOUT]")
+	flag.IntVar(&cfg.retry.attempts, "retry-attempts", cfg.retry.attempts, "number of attempts made for git, go list, and GitHub API operations [GOVANITY_RETRY_ATTEMPTS]")
+	flag.DurationVar(&cfg.retry.baseDelay, "retry-delay", cfg.retry.baseDelay, "delay before the first retry, doubled for each subsequent retry [GOVANITY_RETRY_DELAY]")
+	flag.DurationVar(&cfg.retry.maxDelay, "retry-max-delay", cfg.retry.maxDelay, "maximum delay between retries [GOVANITY_RETRY_MAX_DELAY]")
+	flag.DurationVar(&cfg.retry.rateLimitWait, "rate-limit-wait", cfg.retry.rateLimitWait, "longest to wait for an exceeded GitHub API rate limit to reset before failing, 0 to fail immediately [GOVANITY_RATE_LIMIT_WAIT]")
+	flag.Float64Var(&cfg.retry.jitter, "retry-jitter", cfg.retry.jitter, "fraction of the retry delay to randomly add or subtract, 0 to 1 [GOVANITY_RETRY_JITTER]")
+	flag.StringVar(&cfg.tags, "tags", cfg.tags, "comma separated build tags used when listing packages, to find packages only built with those tags (optional) [GOVANITY_TAGS]")
+	flag.StringVar(&cfg.goos, "goos", cfg.goos, "GOOS used when listing packages, to find packages only built for that operating system (optional) [GOVANITY_GOOS]")
+	flag.StringVar(&cfg.goarch, "goarch", cfg.goarch, "GOARCH used when listing packages, to find packages only built for that architecture (optional) [GOVANITY_GOARCH]")
+	flag.BoolVar(&cfg.cgo, "cgo", cfg.cgo, "enable cgo when listing packages, selecting the files of cgo builds (default: false) [GOVANITY_CGO]")
+	flag.BoolVar(&cfg.caseInsensitive, "case-insensitive", cfg.caseInsensitive, "match import paths to the prefix ignoring case, hosts are always matched ignoring case (default: false) [GOVANITY_CASE_INSENSITIVE]")
+	flag.BoolVar(&cfg.moduleOnly, "module-only", cfg.moduleOnly, "derive import paths from go.mod module paths, ignoring import comments (default: false) [GOVANITY_MODULE_ONLY]")
+	flag.BoolVar(&cfg.issueLinks, "issue-links", cfg.issueLinks, "link the issue tracker of each package on the root index and collection pages (default: false) [GOVANITY_ISSUE_LINKS]")
+	flag.BoolVar(&cfg.openGraph, "opengraph", cfg.openGraph, "include OpenGraph and description meta tags in generated HTML (default: false) [GOVANITY_OPENGRAPH]")
+	flag.BoolVar(&cfg.gitCommit, "git-commit", cfg.gitCommit, "commit the files changed by the run to the git repository containing out (default: false) [GOVANITY_GIT_COMMIT]")
+	flag.StringVar(&cfg.commitMessage, "commit-message", cfg.commitMessage, "template of the git-commit message, given the counts .Added, .Removed, and .Changed of pages and .Files changed (default: "+defaultCommitMessage+") [GOVANITY_COMMIT_MESSAGE]")
+	flag.BoolVar(&cfg.respectGitignore, "respect-gitignore", cfg.respectGitignore, "when out is in a git repository, skip files ignored by git or tracked files not generated by govanity (default: false) [GOVANITY_RESPECT_GITIGNORE]")
+	flag.BoolVar(&cfg.failOnEmpty, "fail-on-empty", cfg.failOnEmpty, "fail without writing files if no packages are found (default: false) [GOVANITY_FAIL_ON_EMPTY]")
+	flag.BoolVar(&cfg.verbose, "v", cfg.verbose, "also log why repositories and packages were skipped and each match found (default: false) [GOVANITY_VERBOSE]")
+	flag.BoolVar(&cfg.quiet, "quiet", cfg.quiet, "only log warnings (default: false) [GOVANITY_QUIET]")
+	flag.StringVar(&cfg.logFormat, "log-format", cfg.logFormat, "format of log messages, one of text, json (default: text) [GOVANITY_LOG_FORMAT]")
+	flag.BoolVar(&cfg.netlify, "netlify", cfg.netlify, "write a _redirects file for Netlify sending visitors of each page to its repository or redirect with a 302, the go command is still served the page (default: false) [GOVANITY_NETLIFY]")
+	flag.StringVar(&cfg.netlifyMode, "netlify-mode", cfg.netlifyMode, "with netlify, alongside to also write the page of each package, or instead to only write the redirects, which leaves the go command unable to resolve the imports (default: alongside) [GOVANITY_NETLIFY_MODE]")
+	flag.BoolVar(&cfg.llmsTxt, "llms-txt", cfg.llmsTxt, "write an llms.txt listing each package with its synopsis and repository (default: false) [GOVANITY_LLMS_TXT]")
+	flag.BoolVar(&cfg.strict, "strict", cfg.strict, "fail when an explicitly listed repository isn't a Go repository, rather than skipping it (default: false) [GOVANITY_STRICT]")
+	flag.BoolVar(&cfg.dryRun, "dry-run", cfg.dryRun, "print the page written for each import and its import prefix instead of writing files, failing if there are none (default: false) [GOVANITY_DRY_RUN]")
+	flag.BoolVar(&cfg.findOrphans, "find-orphans", cfg.findOrphans, "list pages in out which were generated previously but no longer correspond to an import, instead of writing files (default: false) [GOVANITY_FIND_ORPHANS]")
+	flag.BoolVar(&cfg.clean, "clean", cfg.clean, "delete the pages and CNAME previously generated in out which aren't written again, keeping dotfiles and other files, refusing if out has files but no generated pages (default: false) [GOVANITY_CLEAN]")
+	flag.BoolVar(&cfg.prune, "prune", cfg.prune, "delete pages in out which were generated previously but no longer correspond to an import, see -find-orphans (default: false) [GOVANITY_PRUNE]")
+	flag.BoolVar(&cfg.selfTest, "selftest", cfg.selfTest, "check that the page of every import is well-formed HTML with valid go-import and go-source tags, failing if any aren't (default: false) [GOVANITY_SELFTEST]")
+	flag.BoolVar(&cfg.verifySource, "verify-source", cfg.verifySource, "check that a sample of go-source URLs resolve, failing if any don't (default: false) [GOVANITY_VERIFY_SOURCE]")
+	flag.BoolVar(&cfg.majorBranches, "major-branches", cfg.majorBranches, "also scan the branches of each repository named v2, v3, etc. for major versions, cloning each of them (default: false) [GOVANITY_MAJOR_BRANCHES]")
+	flag.BoolVar(&cfg.skipInternal, "skip-internal", cfg.skipInternal, "skip internal packages, which can't be imported from other modules (default: false) [GOVANITY_SKIP_INTERNAL]")
+	flag.BoolVar(&cfg.commands, "commands", cfg.commands, "generate pages for main packages so they can be installed with go install [GOVANITY_COMMANDS]")
+	flag.BoolVar(&cfg.api, "api", cfg.api, "read repositories with the GitHub API instead of cloning them, git and go are not required (default: false) [GOVANITY_API]")
+	flag.StringVar(&cfg.cacheDir, "cache-dir", cfg.cacheDir, "directory clones are kept in between runs, reused while the cloned branch is unchanged (optional) [GOVANITY_CACHE_DIR]")
+	flag.BoolVar(&cfg.noCache, "no-cache", cfg.noCache, "clone every repository, ignoring cache-dir (default: false) [GOVANITY_NO_CACHE]")
+	flag.BoolVar(&cfg.sparse, "sparse", cfg.sparse, "only download the Go files and go.mod of each cloned repository, using a partial clone where supported (default: false) [GOVANITY_SPARSE]")
+	flag.BoolVar(&cfg.tarball, "tarball", cfg.tarball, "download GitHub repositories as tarballs instead of cloning them, git is not required (default: false) [GOVANITY_TARBALL]")
+	flag.BoolVar(&cfg.listPackages, "list-packages", cfg.listPackages, "print the packages found in each repository, and why any were skipped, without writing files (default: false) [GOVANITY_LIST_PACKAGES]")
+	flag.BoolVar(&cfg.progress, "progress", cfg.progress, "show a progress bar instead of logging each repository when stdout is a terminal (default: false) [GOVANITY_PROGRESS]")
+	flag.BoolVar(&cfg.includeForks, "include-forks", cfg.includeForks, "include forks found by searching users or organizations, explicitly listed forks are always included (default: false) [GOVANITY_INCLUDE_FORKS]")
+	flag.BoolVar(&cfg.excludeArchived, "exclude-archived", cfg.excludeArchived, "skip archived repositories, including those explicitly listed (default: false) [GOVANITY_EXCLUDE_ARCHIVED]")
+	flag.BoolVar(&cfg.includeTemplates, "include-templates", cfg.includeTemplates, "include template repositories found by searching users or organizations (default: false) [GOVANITY_INCLUDE_TEMPLATES]")
+	flag.BoolVar(&cfg.normalize, "normalize", cfg.normalize, "re-render existing generated files in out to the current format instead of searching (default: false) [GOVANITY_NORMALIZE]")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: govanity [flags]
 
@@ -63,268 +297,1932 @@ HTML with <go-import> and <go-source> tags will be written to $HOME/src/packag.g
 
 	flag.Parse()
 
-	err := cfg.Parse()
+	// Settings in the configuration file override the environment but not
+	// flags, so note the flags which were set.
+	if cfg.configFile != "" {
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if err := cfg.load(cfg.configFile, set); err != nil {
+			return cfg, fmt.Errorf("loading config: %v", err)
+		}
+	}
+
+	err = cfg.Parse()
 
 	return cfg, err
 }
 
+// envBool reports whether the environment variable name is set to
+// a value other than "" or "0".
+func envBool(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0"
+}
+
+// envBoolDefault is envBool, but returns def if name isn't set.
+func envBoolDefault(name string, def bool) bool {
+	if os.Getenv(name) == "" {
+		return def
+	}
+	return envBool(name)
+}
+
+// envInt returns the integer value of the environment variable name,
+// or def if it isn't set.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return i, nil
+}
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return d, nil
+}
+
+func envFloat(name string, def float64) (float64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return f, nil
+}
+
 func main() {
 	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", redact(err.Error()))
 		os.Exit(1)
 	}
 }
 
-func run() error {
+func run() (err error) {
 	cfg, err := configuration()
 	if err != nil {
 		return err
 	}
+	addSecret(cfg.githubToken)
+	if cfg.githubToken != "" {
+		addSecret(cfg.githubCredentials())
+	}
+	addSecret(cfg.gitlabToken)
+	logFormat = cfg.logFormat
+	switch {
+	case cfg.verbose:
+		logLevel = slog.LevelDebug
+	case cfg.quiet:
+		logLevel = slog.LevelWarn
+	}
 
-	fmt.Printf("Prefix=%q Search List=%+v Out=%q Token=%t Write CNAME=%t\n", cfg.prefix, cfg.searchList, cfg.out, cfg.githubToken != "", cfg.writeCNAME)
+	if cfg.diagOnError != "" {
+		diag = new(diagnostics)
+		defer func() {
+			if err == nil {
+				return
+			}
+			if dErr := writeDiagnostics(cfg.diagOnError, &cfg, diag, err); dErr != nil {
+				warnf("", "writing diagnostics: %v", dErr)
+				return
+			}
+			logf("Wrote diagnostics to %s\n", cfg.diagOnError)
+		}()
+	}
+
+	stats := runStats{start: time.Now()}
+	if cfg.metricsFile != "" {
+		defer func() {
+			if mErr := writeMetrics(cfg.metricsFile, &stats, err, time.Now()); mErr != nil && err == nil {
+				err = fmt.Errorf("writing metrics file: %v", mErr)
+			}
+		}()
+	}
 
-	ctx := context.Background()
+	logf("Prefix=%q Search List=%+v Out=%q Token=%t Write CNAME=%t\n", strings.Join(cfg.prefixes, ","), cfg.searchList, cfg.out, cfg.githubToken != "", cfg.writeCNAME)
 
-	var client *http.Client
-	if cfg.githubToken != "" {
-		client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.githubToken}))
+	// Cancelling kills any running git and go commands, so that their
+	// temp directories are removed before exiting. A second signal
+	// exits immediately.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
 	}
-	gh := github.NewClient(client)
 
-	repoURLs, err := getPotentialRepos(ctx, gh, cfg.searchList)
-	if err != nil {
-		return err
+	if cfg.normalize {
+		if len(cfg.prefixes) < 2 {
+			return normalize(ctx, &cfg)
+		}
+		for _, prefix := range cfg.prefixes {
+			if err := normalize(ctx, cfg.forPrefix(prefix)); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
-	var imports []vanityImport
-	for _, repo := range repoURLs {
-		fmt.Printf("Pulling %s\n", repo)
-		packages, err := getVanityPackages(ctx, repo, cfg.prefix)
+	var (
+		imports []vanityImport
+		failed  int // repositories which couldn't be scanned
+	)
+	if cfg.mappings != "" {
+		imports, err = readMappings(&cfg, cfg.mappings)
 		if err != nil {
-			fmt.Printf("\t%v\n", err)
-			continue
+			return err
+		}
+		stats.addImports(imports)
+	} else {
+		results, err := discover(ctx, &cfg)
+		diag.addResults(results)
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return fmt.Errorf("timed out after %v", cfg.timeout)
+		case context.Canceled:
+			return errors.New("interrupted")
+		}
+		if err != nil {
+			return err
 		}
+		stats.addResults(results)
 
-		for _, pkg := range packages {
-			fmt.Printf("Found match: %s -> %s\n", pkg.Import, pkg.RepoURL)
+		if cfg.listPackages {
+			printPackageReport(results)
+			return nil
 		}
-		fmt.Printf("Found %d matching packages.\n", len(packages))
 
-		imports = append(imports, packages...)
+		for _, result := range results {
+			if result.err != nil {
+				failed++
+			}
+			imports = append(imports, result.imports...)
+		}
 	}
+	defer stats.summarize()
 
-	for _, imprt := range imports {
-		htmlPath := imprt.htmlPath(cfg.prefix, cfg.out)
-		os.MkdirAll(filepath.Dir(htmlPath), 0755)
-		f, err := os.Create(htmlPath)
+	imports = excludeImports(&cfg, imports)
+	imports = dropUnrooted(imports)
+	imports = dedupImports(imports)
+	cfg.applyImportConfig(imports)
+	imports = addMoved(imports, cfg.imports)
+	diag.setImports(imports)
+
+	if cfg.modules != "" {
+		modules, err := readModuleList(&cfg, cfg.modules)
 		if err != nil {
-			fmt.Printf("Error creating %s: %v\n", htmlPath, err)
-			continue
+			return err
 		}
-		defer f.Close()
+		imports = pinModules(imports, modules)
+	}
 
-		if err := tmpl.Execute(f, imprt); err != nil {
-			fmt.Printf("Error writing %s: %v\n", htmlPath, err)
-			continue
+	for i := range imports {
+		if imports[i].Redirect, err = cfg.redirectURL(imports[i]); err != nil {
+			return err
 		}
 	}
 
-	if cfg.writeCNAME {
-		err := ioutil.WriteFile(filepath.Join(cfg.out, "CNAME"), []byte(cfg.prefixURL.Host+"\n"), 0644)
+	if cfg.failOnEmpty && len(imports) == 0 {
+		return errors.New("no packages found")
+	}
+	stats.pages = len(imports)
+
+	if cfg.expect != "" {
+		expected, err := readExpectations(cfg.expect)
 		if err != nil {
-			return fmt.Errorf("writing CNAME file: %v", err)
+			return err
+		}
+		if err := checkExpectations(imports, expected); err != nil {
+			return err
 		}
 	}
 
-	return nil
-}
-
-type config struct {
-	prefix      string
-	prefixURL   *url.URL
-	search      string
-	searchList  []string
-	out         string
-	githubToken string
-	writeCNAME  bool
-}
-
-func (cfg *config) Parse() error {
-	if cfg.prefix == "" {
-		return errors.New("must provide vanity URL prefix")
+	if cfg.serve != "" {
+		return serveImports(ctx, &cfg, cfg.serve, imports)
 	}
 
-	u, err := url.Parse("//" + cfg.prefix)
+	if cfg.patch != "" {
+		err = writePatch(ctx, &cfg, imports)
+	} else if cfg.dryRun {
+		err = dryRun(&cfg, imports)
+	} else if cfg.findOrphans {
+		var orphans []string
+		orphans, err = findOrphans(ctx, &cfg, imports)
+		for _, orphan := range orphans {
+			fmt.Println(orphan)
+		}
+	} else {
+		var w *siteWriter
+		if cfg.clean {
+			err = checkClean(&cfg)
+		}
+		if err == nil {
+			w, err = generateSites(ctx, &cfg, cfg.out, imports)
+		}
+		if err == nil && (cfg.prune || cfg.clean) {
+			if reason := pruneBlocked(ctx, failed); reason != "" {
+				warnf("", "not deleting stale pages, %s", reason)
+			} else if cfg.clean {
+				err = w.clean(&cfg)
+			} else {
+				err = w.prune()
+			}
+		}
+		if err == nil && cfg.changedFiles != "" {
+			err = writeChanges(cfg.changedFiles, w.changes)
+		}
+		if err == nil && cfg.manifest != "" {
+			if err = writeManifest(&cfg, cfg.manifest, imports); err != nil {
+				err = fmt.Errorf("writing manifest: %v", err)
+			}
+		}
+		if err == nil && cfg.integrityFile != "" {
+			err = writeIntegrity(cfg.integrityFile, w.hashes)
+		}
+		if err == nil && cfg.gitCommit {
+			err = commitChanges(ctx, &cfg, w.changes, imports)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("invalid URL (%v)", err)
+		return err
 	}
-	cfg.prefixURL = u
 
-	if cfg.search == "" {
-		return errors.New("search list must contain at least one entry")
+	if cfg.linksFile != "" && !cfg.dryRun {
+		if err := writeLinks(cfg.linksFile, imports); err != nil {
+			return fmt.Errorf("writing links file: %v", err)
+		}
 	}
 
-	for _, search := range strings.Split(cfg.search, ",") {
-		search = strings.TrimSpace(search)
-		if search != "" {
-			cfg.searchList = append(cfg.searchList, search)
+	if cfg.selfTest {
+		if err := selfTest(ctx, &cfg, imports); err != nil {
+			return err
 		}
 	}
-	return nil
-}
 
-func getPotentialRepos(ctx context.Context, gh *github.Client, search []string) (repoURLs []string, _ error) {
-	// Pull out repos and make a map for dup check
-	searchRepos := make(map[string]struct{})
-	var usernames []string
-	for _, v := range search {
-		if !strings.ContainsRune(v, '/') {
-			usernames = append(usernames, v)
-			continue
+	if cfg.verifySource {
+		if err := verifySource(ctx, cfg.httpClient(), imports); err != nil {
+			return err
 		}
+	}
 
-		repoURLs = append(repoURLs, "https://github.com/"+v)
-		searchRepos[v] = struct{}{}
+	if cfg.insecureServe != "" && cfg.patch == "" && !cfg.findOrphans && !cfg.dryRun {
+		return serveInsecure(ctx, &cfg, cfg.insecureServe)
 	}
 
-	for _, username := range usernames {
-		repos, _, err := gh.Repositories.List(ctx, username, nil)
+	return nil
+}
+
+// generate writes the pages for imports and any other requested files
+// to dir, which is normally cfg.out, returning the writer which recorded
+// the files written.
+func generate(ctx context.Context, cfg *config, dir string, imports []vanityImport) (*siteWriter, error) {
+	var outRepo *gitRepo
+	if cfg.respectGitignore {
+		var err error
+		outRepo, err = findGitRepo(ctx, cfg.out)
 		if err != nil {
-			fmt.Printf("%s: %v", username, err)
-			continue
+			return nil, err
 		}
+	}
 
-		for _, repo := range repos {
-			repoName := repo.GetName()
+	w := &siteWriter{dir: dir, postProcess: cfg.postProcess}
+	icons := cfg.icons()
 
-			if _, ok := searchRepos[username+"/"+repoName]; ok {
-				fmt.Printf("%s/%s: is explicitly listed\n", username, repoName)
+	pages := append(imports[:len(imports):len(imports)], cfg.branchVariants(imports)...)
+	if cfg.netlify && cfg.netlifyMode == netlifyInstead {
+		pages = nil
+	}
+	if err := checkRootPage(cfg, dir, pages); err != nil {
+		return nil, err
+	}
+	for _, imprt := range pages {
+		htmlPath := cfg.pagePath(imprt, dir)
+		if outRepo != nil {
+			ok, err := outRepo.shouldWrite(ctx, cfg.pagePath(imprt, cfg.out))
+			if err != nil {
+				warnf("", "checking %s: %v", htmlPath, err)
+				w.keep(htmlPath)
 				continue
 			}
-
-			if repo.GetFork() {
-				fmt.Printf("%s/%s: is a fork\n", username, repoName)
+			if !ok {
+				w.keep(htmlPath)
 				continue
 			}
+		}
 
-			if repo.GetLanguage() == "Go" {
-				repoURLs = append(repoURLs, repo.GetSVNURL())
-				continue
-			}
+		html, err := cfg.renderPage(ctx, imprt)
+		if err == nil {
+			err = w.writeFile(htmlPath, html)
+		}
+		if err != nil {
+			warnf("", "writing %s: %v", htmlPath, err)
+			w.keep(htmlPath)
+			continue
+		}
+	}
 
-			languages, _, err := gh.Repositories.ListLanguages(ctx, username, repoName)
-			if err != nil {
-				fmt.Printf("%s: %v", username, err)
-				continue
-			}
-			if _, ok := languages["Go"]; !ok {
-				fmt.Printf("%s/%s: not a Go repository\n", username, repoName)
-				continue
-			}
+	for _, c := range cfg.collections {
+		htmlPath := c.htmlPath(dir)
+		l := c.list(imports)
+		l.Icons = icons
+		l.IssueLinks = cfg.issueLinks
+		if err := w.writeTemplate(ctx, htmlPath, listTmpl, l); err != nil {
+			warnf("", "writing %s: %v", htmlPath, err)
+			w.keep(htmlPath)
+			continue
+		}
+	}
+
+	if err := writeRoot(ctx, w, cfg, imports); err != nil {
+		return nil, fmt.Errorf("writing root index: %v", err)
+	}
+
+	if err := writeIcons(w, cfg); err != nil {
+		return nil, fmt.Errorf("writing icons: %v", err)
+	}
 
-			repoURLs = append(repoURLs, repo.GetSVNURL())
+	if cfg.writeCNAME {
+		err := w.writeFile(filepath.Join(dir, "CNAME"), []byte(cfg.prefixURL.Host+"\n"))
+		if err != nil {
+			return nil, fmt.Errorf("writing CNAME file: %v", err)
+		}
+	}
+
+	if cfg.llmsTxt {
+		if err := w.writeFile(filepath.Join(dir, "llms.txt"), llmsTxt(cfg.prefix, imports)); err != nil {
+			return nil, fmt.Errorf("writing llms.txt: %v", err)
+		}
+	}
+
+	if cfg.robots != "" {
+		err := w.writeFile(filepath.Join(dir, "robots.txt"), robotsTxt(cfg.robots))
+		if err != nil {
+			return nil, fmt.Errorf("writing robots.txt: %v", err)
+		}
+	}
+
+	if cfg.headersFile != "" {
+		data, err := headers(cfg.headersFile, cfg.cacheControl, cfg.prefix, imports)
+		if err == nil {
+			err = w.writeFile(filepath.Join(dir, "_headers"), data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("writing headers file: %v", err)
+		}
+	}
+
+	if cfg.netlify {
+		if err := w.writeFile(filepath.Join(dir, "_redirects"), netlifyRedirects(cfg.prefix, cfg.layout, cfg.netlifyMode, imports)); err != nil {
+			return nil, fmt.Errorf("writing _redirects: %v", err)
 		}
 	}
-	return repoURLs, nil
+
+	return w, nil
 }
 
-func getVanityPackages(ctx context.Context, url, base string) ([]vanityImport, error) {
-	var imports []vanityImport
+// repoResult is the outcome of scanning a single repository.
+type repoResult struct {
+	url     string
+	imports []vanityImport
+	skipped []skippedPackage
+	err     error
+}
 
-	tmpDir, err := ioutil.TempDir("", "govanity")
+// discover searches GitHub for repositories and returns the result
+// of scanning each of them for vanity imports.
+func discover(ctx context.Context, cfg *config) ([]repoResult, error) {
+	client := cfg.httpClient()
+	if cfg.githubToken != "" {
+		ctx := context.WithValue(ctx, oauth2.HTTPClient, client)
+		client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.githubToken}))
+	}
+	gh, err := newGitHubClient(cfg, client)
 	if err != nil {
 		return nil, err
 	}
-	defer os.RemoveAll(tmpDir)
 
-	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	repoURLs, err := getPotentialRepos(ctx, githubLister{gh}, cfg)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.prefilter > 0 {
+		repoURLs = prefilterRepos(ctx, cfg, githubLister{gh}, repoURLs)
+	}
+	return scanRepos(ctx, gh, cfg, repoURLs)
+}
 
-	cmd := exec.CommandContext(ctx, "git", "clone", "--depth=1", url, tmpDir)
-	if err := cmd.Run(); err != nil {
-		return nil, err
+// scanRepos returns the result of scanning each of repoURLs for vanity
+// imports, aborting once more than -max-failures of them fail.
+func scanRepos(ctx context.Context, gh *github.Client, cfg *config, repoURLs []string) ([]repoResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make([]repoResult, len(repoURLs))
+		limits  = &hostLimits{cfg: cfg}
+		wg      sync.WaitGroup
+
+		mu       sync.Mutex // guards failed and abortErr
+		failed   []string
+		abortErr error
+	)
+	progress := startProgress(cfg, "Scanning", len(repoURLs))
+	defer progress.finish()
+	for i, repo := range repoURLs {
+		wg.Add(1)
+		go func(i int, repo string) {
+			defer wg.Done()
+
+			release, err := limits.acquire(ctx, repo)
+			if err != nil {
+				results[i] = repoResult{url: repo, err: err}
+				return
+			}
+			defer release()
+
+			results[i] = scanRepository(ctx, gh, cfg, progress, repo)
+			if results[i].err == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, repo)
+			if isRateLimited(results[i].err) && abortErr == nil {
+				abortErr = results[i].err
+				cancel()
+			}
+			if cfg.maxFailures >= 0 && len(failed) > cfg.maxFailures && abortErr == nil {
+				abortErr = fmt.Errorf("aborting after %d failed repositories: %s", len(failed), strings.Join(failed, ", "))
+				cancel()
+			}
+		}(i, repo)
 	}
+	wg.Wait()
 
-	cmd = exec.CommandContext(ctx, "go", "list", "-f={{.ImportComment}}:{{.Dir}}", "./...")
-	cmd.Dir = tmpDir
-	out, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
+	if abortErr != nil {
+		// The partial results are returned for diagnostics.
+		return results, abortErr
 	}
+	return results, nil
+}
 
-	if err := cmd.Start(); err != nil {
-		return nil, err
+// scanRepository returns the result of scanning the repository at repo
+// for vanity imports, logging the outcome.
+func scanRepository(ctx context.Context, gh *github.Client, cfg *config, progress *progressBar, repo string) repoResult {
+	progress.step(repo)
+	defer progress.stepDone()
+	if !progress.active() {
+		logf("Pulling %s\n", repo)
 	}
 
-	scanner := bufio.NewScanner(out)
+	var (
+		packages []vanityImport
+		skipped  []skippedPackage
+		err      error
+	)
+	if owner, name, ok := cfg.githubRepo(repo); ok && cfg.orgs[owner].Names {
+		packages, err = nameImport(ctx, cfg, repo, name)
+	} else if ok && cfg.api {
+		packages, skipped, err = getVanityPackagesAPI(ctx, gh, cfg, repo)
+	} else {
+		packages, skipped, err = getVanityPackages(ctx, gh, cfg, repo)
+	}
+	if err != nil {
+		warnf(repo, "%v", err)
+		return repoResult{url: repo, imports: packages, skipped: skipped, err: err}
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, base) {
-			continue
+	if !progress.active() {
+		for _, pkg := range packages {
+			debugf("Found match: %s -> %s\n", pkg.Import, pkg.RepoURL)
 		}
+		logf("Found %d matching packages in %s.\n", len(packages), repo)
+	}
+	return repoResult{url: repo, imports: packages, skipped: skipped}
+}
 
-		s := strings.Split(line, ":")
-		importPath := s[0]
-		dir, err := filepath.EvalSymlinks(s[1])
-		if err != nil {
-			return nil, err
+// printPackageReport prints the packages found in each repository,
+// including those which were skipped and why.
+func printPackageReport(results []repoResult) {
+	for _, result := range results {
+		reportf("\n%s\n", result.url)
+		if result.err != nil {
+			reportf("\terror: %v\n", result.err)
+			continue
 		}
 
-		pathLen := 0
-		if dir != tmpDir {
-			dir = filepath.ToSlash(strings.TrimLeft(strings.TrimPrefix(dir, tmpDir), "/\\"))
-			pathLen = len(strings.Split(dir, "/"))
+		for _, imprt := range result.imports {
+			if imprt.Command {
+				reportf("\tmatched: %s (main)\n", imprt.Import)
+				continue
+			}
+			reportf("\tmatched: %s\n", imprt.Import)
+		}
+		for _, pkg := range result.skipped {
+			reportf("\tskipped: %s (%s)\n", pkg.Path, pkg.Reason)
 		}
+		reportf("\t%d found, %d matched, %d skipped\n", len(result.imports)+len(result.skipped), len(result.imports), len(result.skipped))
+	}
+}
 
-		imports = append(imports, vanityImport{
-			Import:  importPath,
-			RepoURL: url,
-			pathLen: pathLen,
-		})
+type config struct {
+	prefix      string
+	prefixes    []string
+	prefixURL   *url.URL
+	search      string
+	searchList  []string
+	searchFile  string
+	cacheDir    string
+	logFormat   string
+	noCache     bool
+	out         string
+	githubToken string
+	githubURL   string
+	gitlabToken string
+	gitlabURL   string
+	gitlabHost  string
+
+	resolverCommand string
+	writeCNAME      bool
+	configFile      string
+	mappings        string
+	modules         string
+	expect          string
+	perPage         int
+	maxFailures     int
+	prefilter       int
+	concurrency     int
+	cloneTimeout    time.Duration
+	timeout         time.Duration
+	retry           retryPolicy
+
+	// defaultBranches caches the default branch of each repository.
+	defaultBranches *branchCache
+	cgo             bool
+	tags            string
+	goos            string
+	goarch          string
+	openGraph       bool
+	issueLinks      bool
+	moduleOnly      bool
+	caseInsensitive bool
+
+	rootBehavior  string
+	layout        string
+	rootRedirect  string
+	patch         string
+	changedFiles  string
+	metricsFile   string
+	linksFile     string
+	manifest      string
+	integrityFile string
+	insecureServe string
+	commitMessage string
+	diagOnError   string
+	postProcess   string
+	template      string
+	pageTmpl      *template.Template
+	redirect      string
+	redirectTmpl  *texttemplate.Template
+	sourceFormat  string
+	sourceTmpl    *texttemplate.Template
+	serve         string
+	headersFile   string
+	robots        string
+	proxy         string
+	proxyURL      *url.URL
+	cacheControl  string
+	clonePattern  string
+	cloneProto    string
+	matchPattern  string
+	match         *regexp.Regexp
+	exclude       string
+	excludes      []string
+	cloneRewrite  *regexp.Regexp
+	cloneReplace  string
+
+	favicon        string
+	appleTouchIcon string
+
+	respectGitignore bool
+	gitCommit        bool
+	includeTemplates bool
+	includeForks     bool
+	excludeArchived  bool
+	progress         bool
+	selfTest         bool
+	strict           bool
+	findOrphans      bool
+	dryRun           bool
+	llmsTxt          bool
+	netlify          bool
+	netlifyMode      string
+	verbose          bool
+	failOnEmpty      bool
+	quiet            bool
+	listPackages     bool
+	skipInternal     bool
+	majorBranches    bool
+	commands         bool
+	api              bool
+	tarball          bool
+	sparse           bool
+	prune            bool
+	clean            bool
+	verifySource     bool
+	normalize        bool
+
+	collections []collection
+	repos       map[string]repoConfig
+	imports     map[string]importConfig
+	orgs        map[string]orgConfig
+	hosts       map[string]hostConfig
+}
+
+func (cfg *config) Parse() error {
+	prefixes, err := parsePrefixes(cfg.prefix)
+	if err != nil {
+		return fmt.Errorf("invalid URL (%v)", err)
+	}
+	if len(prefixes) == 0 {
+		return errors.New("must provide vanity URL prefix")
+	}
+	if len(prefixes) > 1 && (cfg.serve != "" || cfg.insecureServe != "") {
+		return errors.New("serve and insecure-serve can't be used with more than one prefix")
 	}
+	cfg.prefixes = prefixes
+	cfg.prefix = prefixes[0]
+	cfg.prefixURL, _ = url.Parse("//" + cfg.prefix)
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if cfg.search == "" && cfg.searchFile == "" && cfg.mappings == "" && !cfg.normalize {
+		return errors.New("search list must contain at least one entry")
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return nil, err
+	switch cfg.rootBehavior {
+	case "":
+		cfg.rootBehavior = rootNone
+	case rootIndex, rootNone:
+	case rootRedirect:
+		if cfg.rootRedirect == "" {
+			return errors.New("root-redirect must be provided when root-behavior is redirect")
+		}
+	default:
+		return fmt.Errorf("root-behavior must be %s, %s, or %s", rootIndex, rootRedirect, rootNone)
 	}
 
-	return imports, nil
-}
+	switch cfg.layout {
+	case "":
+		cfg.layout = layoutFile
+	case layoutFile, layoutDir:
+	default:
+		return fmt.Errorf("layout must be %s or %s", layoutFile, layoutDir)
+	}
+	switch cfg.netlifyMode {
+	case "":
+		cfg.netlifyMode = netlifyAlongside
+	case netlifyAlongside:
+	case netlifyInstead:
+		if !cfg.netlify {
+			return errors.New("netlify-mode instead requires netlify")
+		}
+	default:
+		return fmt.Errorf("netlify-mode must be %s or %s", netlifyAlongside, netlifyInstead)
+	}
 
-type vanityImport struct {
-	Import  string
-	RepoURL string
-	pathLen int
-}
+	switch cfg.headersFile {
+	case "", headersNetlify, headersCloudflare:
+	default:
+		return fmt.Errorf("headers-file must be %s or %s", headersNetlify, headersCloudflare)
+	}
+	if cfg.cacheControl == "" {
+		cfg.cacheControl = "public, max-age=300"
+	}
 
-func (i vanityImport) ImportPrefix() string {
-	importURL, err := url.Parse(i.Import)
-	if err != nil {
-		return ""
+	if cfg.githubURL == "" {
+		cfg.githubURL = defaultGitHubURL
+	}
+	cfg.githubURL = strings.TrimSuffix(cfg.githubURL, "/")
+	if u, err := url.Parse(cfg.githubURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("github-url must be an absolute URL")
 	}
 
-	importPathSegments := strings.Split(importURL.Path, "/")
-	importURL.Path = strings.Join(importPathSegments[:len(importPathSegments)-i.pathLen], "/")
+	if cfg.gitlabURL == "" {
+		cfg.gitlabURL = "https://gitlab.com"
+	}
+	cfg.gitlabURL = strings.TrimSuffix(cfg.gitlabURL, "/")
+	gitlabURL, err := url.Parse(cfg.gitlabURL)
+	if err != nil || gitlabURL.Scheme == "" || gitlabURL.Host == "" {
+		return errors.New("gitlab-url must be an absolute URL")
+	}
+	cfg.gitlabHost = gitlabURL.Host
 
-	return importURL.String()
-}
+	if cfg.proxy != "" {
+		u, err := url.Parse(cfg.proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("proxy must be an absolute URL")
+		}
+		if password, ok := u.User.Password(); ok {
+			addSecret(password)
+		}
+		cfg.proxyURL = u
+	}
 
-func (i vanityImport) htmlPath(base, dir string) string {
-	return filepath.Join(dir, strings.TrimPrefix(i.Import, base)) + ".html"
-}
+	switch cfg.robots {
+	case "", robotsAllow, robotsDisallow:
+	default:
+		return fmt.Errorf("robots must be %s or %s", robotsAllow, robotsDisallow)
+	}
+
+	switch cfg.cloneProto {
+	case "":
+		cfg.cloneProto = cloneHTTPS
+	case cloneHTTPS, cloneSSH:
+	default:
+		return fmt.Errorf("clone-proto must be %s or %s", cloneHTTPS, cloneSSH)
+	}
+
+	if cfg.clonePattern != "" {
+		re, err := regexp.Compile(cfg.clonePattern)
+		if err != nil {
+			return fmt.Errorf("invalid clone-pattern (%v)", err)
+		}
+		cfg.cloneRewrite = re
+	}
+
+	if cfg.matchPattern != "" {
+		re, err := regexp.Compile(cfg.matchPattern)
+		if err != nil {
+			return fmt.Errorf("invalid match (%v)", err)
+		}
+		cfg.match = re
+	}
+
+	if cfg.excludes, err = parseExclude(cfg.exclude); err != nil {
+		return fmt.Errorf("invalid exclude (%v)", err)
+	}
+
+	if cfg.perPage < 1 || cfg.perPage > 100 {
+		return errors.New("per-page must be between 1 and 100")
+	}
+
+	if _, err := commitMessage(cfg.commitMessage, changeSummary{}); err != nil {
+		return fmt.Errorf("invalid commit-message (%v)", err)
+	}
+
+	redirectTmpl, err := parseRedirect(cfg.redirect)
+	if err != nil {
+		return fmt.Errorf("invalid redirect (%v)", err)
+	}
+	cfg.redirectTmpl = redirectTmpl
+
+	if cfg.sourceTmpl, err = vanity.ParseSourceFormat(cfg.sourceFormat); err != nil {
+		return fmt.Errorf("invalid go-source-format (%v)", err)
+	}
+
+	cfg.pageTmpl = vanity.PageTemplate
+	if cfg.template != "" {
+		t, err := vanity.ParsePageTemplate(cfg.template)
+		if err != nil {
+			return fmt.Errorf("invalid template (%v)", err)
+		}
+		cfg.pageTmpl = t
+	}
+
+	if cfg.concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	if cfg.prefilter < 0 {
+		return errors.New("prefilter must not be negative")
+	}
+
+	if cfg.retry.attempts < 1 {
+		return errors.New("retry-attempts must be at least 1")
+	}
+	if cfg.retry.baseDelay < 0 || cfg.retry.maxDelay < cfg.retry.baseDelay {
+		return errors.New("retry-delay must not be negative or greater than retry-max-delay")
+	}
+	if cfg.retry.jitter < 0 || cfg.retry.jitter > 1 {
+		return errors.New("retry-jitter must be between 0 and 1")
+	}
+	if cfg.retry.rateLimitWait < 0 {
+		return errors.New("rate-limit-wait must not be negative")
+	}
+	if cfg.cloneTimeout < 0 {
+		return errors.New("clone-timeout must not be negative")
+	}
+	if cfg.timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	for _, search := range strings.Split(cfg.search, ",") {
+		search = strings.TrimSpace(search)
+		if search != "" {
+			cfg.searchList = append(cfg.searchList, search)
+		}
+	}
+	if cfg.noCache {
+		cfg.cacheDir = ""
+	}
+
+	if cfg.verbose && cfg.quiet {
+		return errors.New("v and quiet can't both be set")
+	}
+	switch cfg.logFormat {
+	case "":
+		cfg.logFormat = logText
+	case logText, logJSON:
+	default:
+		return fmt.Errorf("unknown log-format %q", cfg.logFormat)
+	}
+
+	if cfg.searchFile != "" {
+		entries, err := readSearchFile(cfg.searchFile)
+		if err != nil {
+			return fmt.Errorf("reading search-file: %v", err)
+		}
+		cfg.searchList = append(cfg.searchList, entries...)
+	}
+	for _, search := range cfg.searchList {
+		if strings.HasPrefix(search, resolverPrefix) && strings.TrimSpace(cfg.resolverCommand) == "" {
+			return fmt.Errorf("resolver-command must be provided to search %s", search)
+		}
+	}
+	return nil
+}
+
+// tarballRepo returns the owner and name of the repository at url when
+// it's a GitHub repository which should be downloaded as a tarball
+// rather than cloned.
+func (cfg *config) tarballRepo(url string) (owner, repo string, ok bool) {
+	if !cfg.tarball {
+		return "", "", false
+	}
+	return cfg.githubRepo(url)
+}
+
+// Supported -clone-proto values.
+const (
+	cloneHTTPS = "https"
+	cloneSSH   = "ssh"
+)
+
+// cloneURL returns the URL to clone the repository at url from. The
+// -clone-pattern is applied after converting it for -clone-proto.
+func (cfg *config) cloneURL(url string) string {
+	if cfg.cloneProto == cloneSSH {
+		url = sshURL(url)
+	}
+	if cfg.cloneRewrite == nil {
+		return url
+	}
+	return cfg.cloneRewrite.ReplaceAllString(url, cfg.cloneReplace)
+}
+
+// sshURL returns the scp-like SSH URL of the repository at the HTTP or
+// HTTPS repoURL, such as git@github.com:owner/repo.git, or repoURL if
+// it's another kind of URL, such as a local path.
+func sshURL(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return repoURL
+	}
+	return "git@" + u.Hostname() + ":" + strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git") + ".git"
+}
+
+// fileConfig is the format of the file provided via -config.
+type fileConfig struct {
+	// Prefix, Search, Out, Token, and CNAME are the equivalent of
+	// the flags of the same names.
+	Prefix string   `json:"prefix"`
+	Search []string `json:"search"`
+	Out    string   `json:"out"`
+	Token  string   `json:"token"`
+	CNAME  *bool    `json:"cname"`
+
+	Collections []collection            `json:"collections"`
+	Repos       map[string]repoConfig   `json:"repos"`
+	Imports     map[string]importConfig `json:"imports"`
+	Orgs        map[string]orgConfig    `json:"orgs"`
+	Hosts       map[string]hostConfig   `json:"hosts"`
+}
+
+// orgConfig is the configuration of the repositories of a GitHub user
+// or organization, keyed by name.
+type orgConfig struct {
+	// Names gives each Go repository a vanity import of the prefix
+	// followed by the repository name, rather than using the import
+	// comments within it.
+	Names bool `json:"names"`
+}
+
+// importConfig is the configuration of a single vanity import,
+// keyed by import path.
+type importConfig struct {
+	// Tags group the import on the root index.
+	Tags []string `json:"tags"`
+
+	// MovedTo is the new import path of a package which was moved,
+	// its page notes the move.
+	MovedTo string `json:"moved_to"`
+
+	// Redirect sends visitors to the page of MovedTo rather
+	// than the repository.
+	Redirect bool `json:"redirect"`
+}
+
+// repoConfig is the configuration of a single repository,
+// keyed by repository URL.
+type repoConfig struct {
+	// Commit pins the repository to a commit SHA rather than
+	// the default branch.
+	Commit string `json:"commit"`
+
+	// Roots maps subdirectories of the repository to the import
+	// prefix of the packages within them, for repositories where
+	// the directory depth doesn't match the import path.
+	Roots map[string]string `json:"roots"`
+
+	// Variants maps names to branches which get preview pages at
+	// each import path suffixed with @name.
+	Variants map[string]string `json:"variants"`
+
+	// ImportURL is the repository root used in go-import, such as
+	// a clone URL, rather than the repository URL.
+	ImportURL string `json:"import_url"`
+
+	// SourceURL is the web URL used in go-source, rather than the
+	// repository URL.
+	SourceURL string `json:"source_url"`
+
+	// VCS is the version control system of the repository used in
+	// go-import, git if empty.
+	VCS string `json:"vcs"`
+}
+
+// applyImportConfig applies the configuration of each import and its
+// repository from the -config file to imports.
+func (cfg *config) applyImportConfig(imports []vanityImport) {
+	for i, imprt := range imports {
+		imports[i].Tags = cfg.imports[imprt.Import].Tags
+		rc := cfg.repos[imprt.RepoURL]
+		imports[i].GoImportURL = rc.ImportURL
+		imports[i].GoSourceURL = strings.TrimSuffix(rc.SourceURL, "/")
+		imports[i].GoImportVCS = rc.VCS
+		imports[i].GitLab = cfg.isGitLab(imports[i].SourceURL())
+		imports[i].SourceFormat = cfg.sourceTmpl
+	}
+}
+
+// load applies the configuration file at path to cfg. Settings which
+// were also set by a flag, named in flagsSet, are left alone.
+func (cfg *config) load(path string, flagsSet map[string]bool) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return err
+	}
+
+	if fc.Prefix != "" && !flagsSet["prefix"] {
+		cfg.prefix = fc.Prefix
+	}
+	if len(fc.Search) > 0 && !flagsSet["search"] {
+		cfg.search = strings.Join(fc.Search, ",")
+	}
+	if fc.Out != "" && !flagsSet["out"] {
+		cfg.out = fc.Out
+	}
+	if fc.Token != "" && !flagsSet["token"] {
+		cfg.githubToken = fc.Token
+	}
+	if fc.CNAME != nil && !flagsSet["cname"] {
+		cfg.writeCNAME = *fc.CNAME
+	}
+
+	for _, c := range fc.Collections {
+		if c.Name == "" {
+			return errors.New("collections must have a name")
+		}
+	}
+	cfg.collections = fc.Collections
+
+	cfg.repos = make(map[string]repoConfig)
+	for url, rc := range fc.Repos {
+		switch rc.VCS {
+		case "", "git", "hg", "svn", "bzr", "fossil":
+		default:
+			return fmt.Errorf("repos: %s: unknown vcs %q", url, rc.VCS)
+		}
+		cfg.repos[strings.TrimSuffix(url, "/")] = rc
+	}
+	cfg.imports = fc.Imports
+	cfg.orgs = fc.Orgs
+
+	for host, hc := range fc.Hosts {
+		if hc.Concurrency < 0 || hc.Rate < 0 {
+			return fmt.Errorf("hosts: %s: concurrency and rate must not be negative", host)
+		}
+	}
+	cfg.hosts = fc.Hosts
+
+	return nil
+}
+
+func getPotentialRepos(ctx context.Context, gh repoLister, cfg *config) (repoURLs []string, _ error) {
+	search := cfg.searchList
+
+	// Pull out repos and make a map for dup check
+	searchRepos := make(map[string]struct{})
+	var usernames, topicEntries, gitlabEntries, resolverEntries []string
+	for _, v := range search {
+		v = strings.Trim(v, "/")
+		if strings.HasPrefix(v, gitlabPrefix) {
+			gitlabEntries = append(gitlabEntries, strings.Trim(strings.TrimPrefix(v, gitlabPrefix), "/"))
+			continue
+		}
+		if strings.HasPrefix(v, resolverPrefix) {
+			resolverEntries = append(resolverEntries, strings.TrimPrefix(v, resolverPrefix))
+			continue
+		}
+		if strings.HasPrefix(v, topicPrefix) {
+			topicEntries = append(topicEntries, v)
+			continue
+		}
+		if !strings.ContainsRune(v, '/') {
+			usernames = append(usernames, v)
+			continue
+		}
+
+		searchRepos[v] = struct{}{}
+
+		// Explicitly listed repositories aren't otherwise checked
+		// for Go, so catch a repository listed by mistake before
+		// cloning it.
+		owner, repoName := path.Split(v)
+		owner = strings.TrimSuffix(owner, "/")
+		if cfg.excludeArchived {
+			var repo *repository
+			err := cfg.retry.do(ctx, func() (err error) {
+				repo, err = gh.getRepository(ctx, owner, repoName)
+				return err
+			})
+			if isRateLimited(err) {
+				return nil, err
+			}
+			if err != nil {
+				warnf(v, "checking if archived: %v", err)
+			} else if repo.GetArchived() {
+				debugf("%s: is archived\n", v)
+				continue
+			}
+		}
+
+		var languages map[string]int
+		err := cfg.retry.do(ctx, func() (err error) {
+			languages, err = gh.listLanguages(ctx, owner, repoName)
+			return err
+		})
+		if isRateLimited(err) {
+			return nil, err
+		}
+		if err != nil {
+			warnf(v, "checking languages: %v", err)
+		} else if _, ok := languages["Go"]; !ok {
+			if cfg.strict {
+				return nil, fmt.Errorf("%s: explicitly listed but not a Go repository", v)
+			}
+			warnf(v, "explicitly listed but not a Go repository, skipping")
+			continue
+		}
+
+		repoURLs = append(repoURLs, cfg.githubURL+"/"+v)
+	}
+
+	progress := startProgress(cfg, "Searching", len(usernames)+len(topicEntries)+len(gitlabEntries)+len(resolverEntries))
+	defer progress.finish()
+	for _, username := range usernames {
+		progress.step(username)
+		repos, err := listAllRepositories(ctx, gh, cfg, username)
+		if isRateLimited(err) {
+			// Carrying on would silently leave out the repositories
+			// of every remaining user.
+			return nil, err
+		}
+		if err != nil {
+			warnf(username, "%v", err)
+			progress.stepDone()
+			continue
+		}
+
+		urls, err := goRepositories(ctx, gh, cfg, searchRepos, username, username, repos)
+		if err != nil {
+			return nil, err
+		}
+		repoURLs = append(repoURLs, urls...)
+		progress.stepDone()
+	}
+
+	// Repositories found by a topic may also belong to a listed user.
+	found := make(map[string]bool, len(repoURLs))
+	for _, u := range repoURLs {
+		found[u] = true
+	}
+	for _, entry := range topicEntries {
+		progress.step(entry)
+		repos, err := searchAllRepositories(ctx, gh, cfg, entry)
+		if isRateLimited(err) {
+			return nil, err
+		}
+		if err != nil {
+			warnf(entry, "%v", err)
+			progress.stepDone()
+			continue
+		}
+
+		urls, err := goRepositories(ctx, gh, cfg, searchRepos, entry, "", repos)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range urls {
+			if !found[u] {
+				found[u] = true
+				repoURLs = append(repoURLs, u)
+			}
+		}
+		progress.stepDone()
+	}
+
+	for _, entry := range gitlabEntries {
+		progress.step(gitlabPrefix + entry)
+		urls, err := gitlabRepos(ctx, cfg, entry)
+		if err != nil {
+			warnf(gitlabPrefix+entry, "%v", err)
+		}
+		for _, u := range urls {
+			if _, ok := searchRepos[u]; ok {
+				continue
+			}
+			searchRepos[u] = struct{}{}
+			repoURLs = append(repoURLs, u)
+		}
+		progress.stepDone()
+	}
+
+	for _, entry := range resolverEntries {
+		progress.step(resolverPrefix + entry)
+		urls, err := resolveRepos(ctx, cfg, entry)
+		if err != nil {
+			warnf(resolverPrefix+entry, "%v", err)
+		}
+		for _, u := range urls {
+			if _, ok := searchRepos[u]; ok {
+				continue
+			}
+			searchRepos[u] = struct{}{}
+			repoURLs = append(repoURLs, u)
+		}
+		progress.stepDone()
+	}
+	return excludeRepos(cfg, repoURLs), nil
+}
+
+// goRepositories returns the URLs of the Go repositories of repos, found
+// by the search entry, skipping those which are explicitly listed and the
+// forks, archived repositories, and templates that aren't included. The
+// repositories are owned by owner, or their own owners if it's empty.
+func goRepositories(ctx context.Context, gh repoLister, cfg *config, searchRepos map[string]struct{}, entry, owner string, repos []*repository) ([]string, error) {
+	var urls []string
+	for _, repo := range repos {
+		repoOwner, repoName := owner, repo.GetName()
+		if repoOwner == "" {
+			repoOwner = repo.Owner.GetLogin()
+		}
+
+		if _, ok := searchRepos[repoOwner+"/"+repoName]; ok {
+			debugf("%s/%s: is explicitly listed\n", repoOwner, repoName)
+			continue
+		}
+
+		if reason := cfg.repoFilter().SkipReason(repo); reason != "" {
+			debugf("%s/%s: %s\n", repoOwner, repoName, reason)
+			continue
+		}
+
+		// The listing includes the default branch, saving a
+		// lookup when scanning.
+		if branch := repo.GetDefaultBranch(); branch != "" {
+			cfg.defaultBranches.set(repo.GetSVNURL(), branch)
+		}
+
+		if repo.GetLanguage() == "Go" {
+			urls = append(urls, repo.GetSVNURL())
+			continue
+		}
+
+		var languages map[string]int
+		err := cfg.retry.do(ctx, func() (err error) {
+			languages, err = gh.listLanguages(ctx, repoOwner, repoName)
+			return err
+		})
+		if isRateLimited(err) {
+			return nil, err
+		}
+		if err != nil {
+			warnf(entry, "%v", err)
+			continue
+		}
+		if _, ok := languages["Go"]; !ok {
+			debugf("%s/%s: not a Go repository\n", repoOwner, repoName)
+			continue
+		}
+
+		urls = append(urls, repo.GetSVNURL())
+	}
+	return urls, nil
+}
+
+// repoFilter returns the vanity.RepoFilter of the repositories found by
+// searching.
+func (cfg *config) repoFilter() vanity.RepoFilter {
+	return vanity.RepoFilter{
+		IncludeForks:     cfg.includeForks,
+		ExcludeArchived:  cfg.excludeArchived,
+		IncludeTemplates: cfg.includeTemplates,
+	}
+}
+
+func getVanityPackages(ctx context.Context, gh *github.Client, cfg *config, url string) ([]vanityImport, []skippedPackage, error) {
+	rc := cfg.repos[url]
+	if rc.VCS != "" && rc.VCS != "git" {
+		return nil, nil, fmt.Errorf("scanning %s repositories isn't supported, list their packages with -mappings", rc.VCS)
+	}
+
+	imports, skipped, err := scanRepo(ctx, gh, cfg, url, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rc.Commit != "" || !cfg.majorBranches {
+		return imports, skipped, nil
+	}
+
+	// Major versions may also be maintained on branches named v2, v3, etc.
+	var branches []string
+	if owner, repo, ok := cfg.tarballRepo(url); ok {
+		branches, err = apiMajorBranches(ctx, gh, cfg, owner, repo)
+	} else {
+		err = cfg.retry.do(ctx, func() (err error) {
+			branches, err = majorBranches(ctx, cfg, cfg.cloneURL(url))
+			return err
+		})
+	}
+	if isRateLimited(err) || ctx.Err() != nil {
+		return nil, nil, err
+	}
+	if err != nil {
+		// The default branch was scanned successfully, so its
+		// packages are kept.
+		warnf(url, "listing major version branches: %v", err)
+		branches = nil
+	}
+
+	onDefault := make(map[string]bool, len(imports))
+	for _, imprt := range imports {
+		onDefault[imprt.Import] = true
+	}
+	for _, branch := range branches {
+		branchImports, branchSkipped, err := scanRepo(ctx, gh, cfg, url, branch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("branch %s: %v", branch, err)
+		}
+		// The default branch's page is kept when a branch has a
+		// package with the same import path.
+		for _, imprt := range branchImports {
+			if onDefault[imprt.Import] {
+				skipped = append(skipped, skippedPackage{Path: imprt.Import, Reason: "already on the default branch"})
+				continue
+			}
+			imports = append(imports, imprt)
+		}
+		skipped = append(skipped, branchSkipped...)
+	}
+
+	return imports, skipped, nil
+}
+
+// skippedPackage is a package found while scanning which doesn't
+// get a vanity import.
+type skippedPackage = vanity.Skipped
+
+// scanRepo clones branch of the repository at url, the default branch if
+// empty, and returns the vanity imports found within it.
+func scanRepo(ctx context.Context, gh *github.Client, cfg *config, url, branch string) ([]vanityImport, []skippedPackage, error) {
+	ref := branch
+	commit := cfg.repos[url].Commit
+	if commit != "" && branch == "" {
+		ref = commit
+	}
+	owner, repo, tarball := cfg.tarballRepo(url)
+	fetch := func(dir string) error {
+		fetchCtx := ctx
+		if cfg.cloneTimeout > 0 {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(ctx, cfg.cloneTimeout)
+			defer cancel()
+		}
+		var err error
+		if tarball {
+			err = downloadTarball(fetchCtx, gh, cfg, owner, repo, ref, dir)
+		} else {
+			err = cfg.retry.do(fetchCtx, func() error {
+				// Start each attempt from an empty directory.
+				if err := emptyDir(dir); err != nil {
+					return err
+				}
+				if commit != "" && branch == "" {
+					return cloneCommit(fetchCtx, cfg, cfg.cloneURL(url), commit, dir)
+				}
+				return clone(fetchCtx, cfg, cfg.cloneURL(url), branch, dir)
+			})
+		}
+		// The clone is killed at the deadline, which git reports
+		// unhelpfully.
+		if err != nil && fetchCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return fmt.Errorf("fetching timed out after %v", cfg.cloneTimeout)
+		}
+		return err
+	}
+
+	var (
+		tmpDir string
+		err    error
+	)
+	if cfg.cacheDir != "" && !tarball {
+		var unlock func()
+		tmpDir, unlock, err = cachedClone(ctx, cfg, url, branch, commit, fetch)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer unlock()
+	} else {
+		tmpDir, err = ioutil.TempDir("", "govanity")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(tmpDir)
+
+		tmpDir, err = filepath.EvalSymlinks(tmpDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := fetch(tmpDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var skipped []skippedPackage
+	scan := cfg.scanConfig()
+	scan.Repos = []vanity.Repo{{URL: url, Branch: ref, Dir: tmpDir, Roots: cfg.repos[url].Roots}}
+	scan.OnSkip = func(repoURL string, pkg vanity.Skipped) {
+		if pkg.Path == repoURL {
+			debugf("%s: %s\n", repoURL, pkg.Reason)
+			return
+		}
+		skipped = append(skipped, pkg)
+	}
+	imports, err := vanity.Discover(ctx, scan)
+	var repoErr *vanity.RepoError
+	if errors.As(err, &repoErr) {
+		// Warnings are already prefixed with the repository.
+		err = repoErr.Err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ref == "" && len(imports) > 0 {
+		ref, err = cfg.defaultBranches.get(url, func() (string, error) {
+			if tarball {
+				return apiDefaultBranch(ctx, gh, cfg, owner, repo)
+			}
+			return headBranch(ctx, tmpDir)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("finding default branch: %v", err)
+		}
+		for i := range imports {
+			imports[i].Branch = ref
+		}
+	}
+	return imports, skipped, nil
+}
+
+// scanConfig returns the configuration of vanity.Discover, without its
+// Repos.
+func (cfg *config) scanConfig() vanity.Config {
+	return vanity.Config{
+		Prefixes:        cfg.prefixes,
+		CaseInsensitive: cfg.caseInsensitive,
+		Match:           cfg.match,
+		ModuleOnly:      cfg.moduleOnly,
+		SkipInternal:    cfg.skipInternal,
+		Commands:        cfg.commands,
+		Tags:            cfg.tags,
+		GOOS:            cfg.goos,
+		GOARCH:          cfg.goarch,
+		CGO:             cfg.cgo,
+	}
+}
+
+// emptyDir replaces dir with an empty directory.
+func emptyDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Mkdir(dir, 0700)
+}
+
+// killWaitDelay is how long to wait for the output of a killed command to
+// be closed. Processes it started, such as git-remote-https, may keep it
+// open until they exit.
+const killWaitDelay = time.Second
+
+// commandContext is exec.CommandContext for commands which are killed when
+// ctx is done, waiting at most killWaitDelay for their output afterwards.
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = killWaitDelay
+	return cmd
+}
+
+// clone makes a shallow clone of branch of the repository at url into dir.
+// The default branch is cloned if branch is empty.
+func clone(ctx context.Context, cfg *config, url, branch, dir string) error {
+	args := []string{"clone", "--quiet", "--depth=1"}
+	if cfg.sparse {
+		// The files are checked out by sparseCheckout.
+		args = append(args, "--filter=blob:none", "--no-checkout")
+	}
+	if branch != "" {
+		args = append(args, "--branch="+branch)
+	}
+	cmd := commandContext(ctx, "git", append(args, url, dir)...)
+	cmd.Env = cfg.gitEnv()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// The output may contain the URL, it's redacted when logged.
+		return gitError(fmt.Errorf("git clone: %v: %s", err, bytes.TrimSpace(out)), out)
+	}
+	if cfg.sparse {
+		return sparseCheckout(ctx, cfg, url, dir)
+	}
+	return nil
+}
+
+// cloneCommit checks out commit of the repository at url into dir. Only
+// the commit itself is fetched when the server allows it, otherwise the
+// full repository is cloned.
+func cloneCommit(ctx context.Context, cfg *config, url, commit, dir string) error {
+	git := func(args ...string) error {
+		cmd := commandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = cfg.gitEnv()
+		// Output captures stderr for retryable.
+		_, err := cmd.Output()
+		return err
+	}
+
+	if err := git("init", "--quiet"); err != nil {
+		return err
+	}
+	if err := git("fetch", "--quiet", "--depth=1", url, commit); err != nil {
+		if err := git("fetch", "--quiet", url); err != nil {
+			return err
+		}
+		return git("checkout", "--quiet", commit)
+	}
+	return git("checkout", "--quiet", "FETCH_HEAD")
+}
+
+// majorBranchRE matches major version branch names.
+var majorBranchRE = regexp.MustCompile(`^v[2-9][0-9]*$`)
+
+// majorBranches returns the major version branches (v2, v3, etc.)
+// of the repository at url.
+func majorBranches(ctx context.Context, cfg *config, url string) ([]string, error) {
+	cmd := commandContext(ctx, "git", "ls-remote", "--heads", url)
+	cmd.Env = cfg.gitEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if branch := strings.TrimPrefix(fields[1], "refs/heads/"); majorBranchRE.MatchString(branch) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+// gitRepo is a git working tree which generated files are written into.
+type gitRepo struct {
+	dir string
+}
+
+// findGitRepo returns the git working tree containing dir. A nil *gitRepo
+// is returned if dir isn't inside of a working tree.
+func findGitRepo(ctx context.Context, dir string) (*gitRepo, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	cmd := commandContext(ctx, "git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &gitRepo{dir: strings.TrimSpace(string(out))}, nil
+}
+
+// shouldWrite reports whether the file at path may be written. Files ignored
+// by git and tracked files which weren't generated by govanity are skipped.
+func (r *gitRepo) shouldWrite(ctx context.Context, path string) (bool, error) {
+	// git runs in the top level of the working tree, rather than the
+	// directory path is relative to.
+	rel, err := r.relPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	ignored, err := r.git(ctx, "check-ignore", "-q", rel)
+	if err != nil {
+		return false, err
+	}
+	if ignored {
+		debugf("%s: ignored by git, skipping\n", path)
+		return false, nil
+	}
+
+	tracked, err := r.git(ctx, "ls-files", "--error-unmatch", rel)
+	if err != nil {
+		return false, err
+	}
+	if !tracked {
+		return true, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if !isGenerated(data) {
+		warnf("", "%s: conflicts with tracked file not generated by govanity, skipping", path)
+		return false, nil
+	}
+	return true, nil
+}
+
+// relPath returns path relative to the top level of the working tree.
+// Symlinks are resolved, as they are in the top level reported by git,
+// in the part of path which exists.
+func (r *gitRepo) relPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for dir, rest := path, ""; ; {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			path = filepath.Join(resolved, rest)
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+	return filepath.Rel(r.dir, path)
+}
+
+// isGenerated reports whether the page data was generated by govanity.
+func isGenerated(data []byte) bool {
+	return bytes.Contains(data, []byte(`<meta name="go-import"`))
+}
+
+// git runs a git command in the repository, reporting whether
+// it exited successfully.
+func (r *gitRepo) git(ctx context.Context, args ...string) (bool, error) {
+	cmd := commandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// vanityImport is the vanity import of a package, see vanity.Import.
+type vanityImport = vanity.Import
+
+// dropUnrooted returns imports without those whose repository root
+// import path can't be found, logging a warning for each.
+func dropUnrooted(imports []vanityImport) []vanityImport {
+	var kept []vanityImport
+	for _, imprt := range imports {
+		if imprt.ImportPrefix() == "" {
+			warnf(imprt.RepoURL, "%s: import path is shallower than its directory, skipping", imprt.Import)
+			continue
+		}
+		kept = append(kept, imprt)
+	}
+	return kept
+}
+
+// dedupImports returns imports with only the first of those sharing an
+// import path, warning when they're found in different repositories.
+func dedupImports(imports []vanityImport) []vanityImport {
+	var (
+		kept []vanityImport
+		seen = make(map[string]string) // import path to repository URL
+	)
+	for _, imprt := range imports {
+		repoURL, ok := seen[imprt.Import]
+		if !ok {
+			seen[imprt.Import] = imprt.RepoURL
+			kept = append(kept, imprt)
+			continue
+		}
+		if repoURL != imprt.RepoURL {
+			warnf("", "%s: found in both %s and %s, using %s", imprt.Import, repoURL, imprt.RepoURL, repoURL)
+		}
+	}
+	return kept
+}
+
+// urlPath returns the path the import is served at, relative to the prefix.
+func urlPath(i vanityImport, base string) string {
+	return "/" + strings.TrimLeft(trimImportPrefix(i.Import, base), "/") + i.VariantSuffix()
+}
+
+// Supported -layout values.
+const (
+	layoutFile = "file"
+	layoutDir  = "dir"
+)
+
+// htmlPath returns the path of the import's page in dir, named for the
+// layout so that it's served at urlPath without the .html extension. The
+// page of the import at base itself is the index.html of dir with either
+// layout.
+func htmlPath(i vanityImport, base, dir, layout string) string {
+	p := filepath.Join(dir, strings.TrimLeft(trimImportPrefix(i.Import, base), "/")+i.VariantSuffix())
+	if p == filepath.Clean(dir) || layout == layoutDir {
+		return filepath.Join(p, "index.html")
+	}
+	return p + ".html"
+}
+
+// collection is a named group of imports which is given its own
+// landing page listing the members.
+type collection struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"`
+	Imports []string `json:"imports"`
+}
+
+func (c collection) htmlPath(dir string) string {
+	return filepath.Join(dir, "collections", c.Name) + ".html"
+}
+
+// list returns the members of c found in imports, sorted by import path.
+func (c collection) list(imports []vanityImport) importList {
+	l := importList{Title: c.Title}
+	if l.Title == "" {
+		l.Title = c.Name
+	}
+
+	for _, name := range c.Imports {
+		found := false
+		for _, imprt := range imports {
+			if imprt.Import == name {
+				l.Imports = append(l.Imports, imprt)
+				found = true
+				break
+			}
+		}
+		if !found {
+			warnf("", "collection %s: %s was not found", c.Name, name)
+		}
+	}
+
+	sort.Slice(l.Imports, func(i, j int) bool {
+		return l.Imports[i].Import < l.Imports[j].Import
+	})
+	return l
+}
+
+// Supported -root-behavior values.
+const (
+	rootIndex    = "index"
+	rootRedirect = "redirect"
+	rootNone     = "none"
+)
+
+// checkRootPage returns an error if one of pages, the page of an import
+// at the prefix itself, would be written to the index.html at the root of
+// dir which -root-behavior also writes.
+func checkRootPage(cfg *config, dir string, pages []vanityImport) error {
+	if cfg.rootBehavior == rootNone {
+		return nil
+	}
+	index := filepath.Join(dir, "index.html")
+	for _, imprt := range pages {
+		if cfg.pagePath(imprt, dir) == index {
+			return fmt.Errorf("the page of %s is %s, which root-behavior %s also writes, set root-behavior to none", imprt.Import, index, cfg.rootBehavior)
+		}
+	}
+	return nil
+}
+
+// writeRoot writes the index.html at the root of the output directory
+// according to cfg.rootBehavior.
+func writeRoot(ctx context.Context, w *siteWriter, cfg *config, imports []vanityImport) error {
+	htmlPath := filepath.Join(w.dir, "index.html")
+	switch cfg.rootBehavior {
+	case rootIndex:
+		l := importList{Title: cfg.prefix, Imports: append([]vanityImport(nil), imports...), Icons: cfg.icons(), IssueLinks: cfg.issueLinks}
+		sort.Slice(l.Imports, func(i, j int) bool {
+			return l.Imports[i].Import < l.Imports[j].Import
+		})
+		l.Groups = groupByTag(l.Imports)
+		return w.writeTemplate(ctx, htmlPath, listTmpl, l)
+	case rootRedirect:
+		return w.writeTemplate(ctx, htmlPath, redirectTmpl, cfg.rootRedirect)
+	}
+	return nil
+}
+
+// Values of -robots.
+const (
+	robotsAllow    = "allow"
+	robotsDisallow = "disallow"
+)
+
+// robotsTxt returns a robots.txt allowing or disallowing all crawlers.
+func robotsTxt(robots string) []byte {
+	if robots == robotsDisallow {
+		return []byte("User-agent: *\nDisallow: /\n")
+	}
+	return []byte("User-agent: *\nDisallow:\n")
+}
+
+// renderPage returns the page of imprt, rendered with the page template
+// and piped through -post-process-command, the same whether it's written,
+// served, normalized, or self tested.
+func (cfg *config) renderPage(ctx context.Context, imprt vanityImport) ([]byte, error) {
+	var buf bytes.Buffer
+	opts := vanity.RenderOptions{Template: cfg.pageTmpl, OpenGraph: cfg.openGraph, Icons: cfg.icons()}
+	if err := imprt.RenderHTML(&buf, opts); err != nil {
+		return nil, err
+	}
+	return postProcess(ctx, cfg.postProcess, buf.Bytes())
+}
+
+// importList is the data passed to listTmpl.
+type importList struct {
+	Title      string
+	Imports    []vanityImport
+	Icons      vanity.Icons
+	IssueLinks bool // link the issue tracker of each import
+
+	// Groups, if any, are listed instead of Imports.
+	Groups []importGroup
+}
+
+// Sections returns the groups of the list, or a single untitled group
+// of all of the imports if there aren't any.
+func (l importList) Sections() []importGroup {
+	groups := l.Groups
+	if len(groups) == 0 {
+		groups = []importGroup{{Imports: l.Imports}}
+	}
+	sections := make([]importGroup, len(groups))
+	for i, g := range groups {
+		g.IssueLinks = l.IssueLinks
+		sections[i] = g
+	}
+	return sections
+}
+
+// importGroup is the imports of an importList with the same tag.
+type importGroup struct {
+	Tag        string
+	Imports    []vanityImport
+	IssueLinks bool
+}
+
+// groupByTag returns imports grouped by tag, sorted by tag with untagged
+// imports last. Imports with several tags are in each of their groups. No
+// groups are returned if none of the imports are tagged.
+func groupByTag(imports []vanityImport) []importGroup {
+	var (
+		groups   = make(map[string][]vanityImport)
+		untagged []vanityImport
+	)
+	for _, imprt := range imports {
+		for _, tag := range imprt.Tags {
+			groups[tag] = append(groups[tag], imprt)
+		}
+		if len(imprt.Tags) == 0 {
+			untagged = append(untagged, imprt)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var sorted []importGroup
+	for tag, imports := range groups {
+		sorted = append(sorted, importGroup{Tag: tag, Imports: imports})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Tag < sorted[j].Tag
+	})
+	if len(untagged) > 0 {
+		sorted = append(sorted, importGroup{Tag: "Other", Imports: untagged})
+	}
+	return sorted
+}
+
+var listTmpl = template.Must(template.Must(template.New("list").Parse(vanity.IconsTemplate + `{{define "imports"}}
+  <ul>
+{{- $issues := .IssueLinks}}
+{{- range .Imports}}
+    <li><a href="https://{{.Import}}">{{.Import}}</a> (<a href="{{.RepoURL}}">source</a>
+    {{- if $issues}}, <a href="{{.IssuesURL}}">report an issue</a>{{end}})</li>
+{{- end}}
+  </ul>
+{{- end}}`)).Parse(`<!DOCTYPE html>
+<head>
+  <meta http-equiv="content-type" content="text/html; charset=utf-8">
+  <title>{{.Title}}</title>
+{{- template "icons" .Icons}}
+</head>
+<body>
+  <h1>{{.Title}}</h1>
+{{- range .Sections}}
+{{- with .Tag}}
+  <h2 id="{{.}}">{{.}}</h2>
+{{- end}}
+  {{- template "imports" .}}
+{{- end}}
+</body>
+</html>
+`))
 
-var tmpl = template.Must(template.New("tmpl").Parse(`<!DOCTYPE html>
+var redirectTmpl = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
 <head>
   <meta http-equiv="content-type" content="text/html; charset=utf-8">
-  <meta name="go-import" content="{{.ImportPrefix}} git {{.RepoURL}}">
-  <meta name="go-source" content="{{.ImportPrefix}} {{.RepoURL}} {{.RepoURL}}/tree/master{/dir} {{.RepoURL}}/blob/master{/dir}/{file}#L{line}">
-  <meta http-equiv="refresh" content="0; url={{.RepoURL}}">
+  <meta http-equiv="refresh" content="0; url={{.}}">
 </head>
 </html>
 `))
@@ -0,0 +1,872 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// gitInit makes dir a git repository with files committed. Commits made
+// by the test are authored by a test identity.
+func gitInit(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	writeTestFiles(t, dir, files)
+	git(t, dir, "init", "--quiet")
+	git(t, dir, "symbolic-ref", "HEAD", "refs/heads/master")
+	gitCommit(t, dir)
+}
+
+// gitCommit commits every change in the git repository at dir.
+func gitCommit(t *testing.T, dir string) {
+	t.Helper()
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "--quiet", "--allow-empty", "-m", "test")
+}
+
+// git runs git with args in dir.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", args[0], err, out)
+	}
+}
+
+// testConfig returns the configuration of a run with prefix and no retries.
+func testConfig(prefix string) *config {
+	cfg := &config{
+		prefix:          prefix,
+		prefixes:        []string{prefix},
+		retry:           retryPolicy{attempts: 1},
+		defaultBranches: new(branchCache),
+		commands:        true,
+	}
+	cfg.prefixURL, _ = url.Parse("//" + prefix)
+	return cfg
+}
+
+func TestGitRepoShouldWrite(t *testing.T) {
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		".gitignore":        "site/ignored.html\n",
+		"site/tracked.html": "<html>by hand</html>",
+		"site/old.html":     testPage,
+		"sub/README":        "",
+	})
+
+	// -out is relative to the working directory, which isn't the top
+	// level of the working tree.
+	t.Chdir(filepath.Join(dir, "sub"))
+	repo, err := findGitRepo(context.Background(), "../site")
+	if err != nil || repo == nil {
+		t.Fatalf("findGitRepo() = %v, %v", repo, err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "../site/new.html", want: true},
+		{path: "../site/new/index.html", want: true},
+		{path: "../site/old.html", want: true},
+		{path: "../site/ignored.html", want: false},
+		{path: "../site/tracked.html", want: false},
+	}
+	for _, tt := range tests {
+		got, err := repo.shouldWrite(context.Background(), tt.path)
+		if err != nil {
+			t.Errorf("shouldWrite(%q): %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("shouldWrite(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGetVanityPackagesMajorVersions(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"go.mod":    "module pack.ag/mv\n",
+		"mv.go":     "package mv\n",
+		"v2/go.mod": "module pack.ag/mv/v2\n",
+		"v2/mv.go":  "package mv\n",
+	})
+	git(t, dir, "checkout", "--quiet", "-b", "v3")
+	writeTestFiles(t, dir, map[string]string{"go.mod": "module pack.ag/mv/v3\n"})
+	gitCommit(t, dir)
+	git(t, dir, "checkout", "--quiet", "master")
+
+	type found struct {
+		Import, Branch, ImportPrefix, SourceDir string
+	}
+	tests := []struct {
+		name          string
+		majorBranches bool
+		want          []found
+	}{
+		{
+			name: "nested module",
+			want: []found{
+				{Import: "pack.ag/mv", Branch: "master", ImportPrefix: "pack.ag/mv", SourceDir: dir + "/tree/master{/dir}"},
+				{Import: "pack.ag/mv/v2", Branch: "master", ImportPrefix: "pack.ag/mv", SourceDir: dir + "/tree/master{/dir}"},
+			},
+		},
+		{
+			name:          "major branches",
+			majorBranches: true,
+			want: []found{
+				{Import: "pack.ag/mv", Branch: "master", ImportPrefix: "pack.ag/mv", SourceDir: dir + "/tree/master{/dir}"},
+				{Import: "pack.ag/mv/v2", Branch: "master", ImportPrefix: "pack.ag/mv", SourceDir: dir + "/tree/master{/dir}"},
+				// v2 on the v3 branch keeps the default branch's page.
+				{Import: "pack.ag/mv/v3", Branch: "v3", ImportPrefix: "pack.ag/mv/v3", SourceDir: dir + "/tree/v3{/dir}"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.moduleOnly = true
+			cfg.majorBranches = tt.majorBranches
+			imports, _, err := getVanityPackages(context.Background(), nil, cfg, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []found
+			for _, imprt := range imports {
+				got = append(got, found{imprt.Import, imprt.Branch, imprt.ImportPrefix(), imprt.SourceDir()})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imports = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLPath(t *testing.T) {
+	tests := []struct {
+		imprt   string
+		base    string
+		variant string
+		layout  string
+		want    string
+	}{
+		{imprt: "pack.ag/amqp", base: "pack.ag", layout: layoutFile, want: "out/amqp.html"},
+		{imprt: "pack.ag/amqp", base: "pack.ag", layout: layoutDir, want: "out/amqp/index.html"},
+		{imprt: "pack.ag/amqp/internal/encoding", base: "pack.ag", layout: layoutFile, want: "out/amqp/internal/encoding.html"},
+		{imprt: "pack.ag/amqp/internal/encoding", base: "pack.ag", layout: layoutDir, want: "out/amqp/internal/encoding/index.html"},
+		{imprt: "example.com/go/amqp", base: "example.com/go", layout: layoutFile, want: "out/amqp.html"},
+		{imprt: "pack.ag/amqp", base: "pack.ag", variant: "dev", layout: layoutFile, want: "out/amqp@dev.html"},
+		{imprt: "pack.ag/amqp", base: "pack.ag", variant: "dev", layout: layoutDir, want: "out/amqp@dev/index.html"},
+		// The import at the prefix is the root index with either layout.
+		{imprt: "pack.ag", base: "pack.ag", layout: layoutFile, want: "out/index.html"},
+		{imprt: "pack.ag", base: "pack.ag", layout: layoutDir, want: "out/index.html"},
+		{imprt: "example.com/go", base: "example.com/go", layout: layoutDir, want: "out/index.html"},
+		{imprt: "pack.ag", base: "pack.ag", variant: "dev", layout: layoutFile, want: "out/@dev.html"},
+		{imprt: "pack.ag", base: "pack.ag", variant: "dev", layout: layoutDir, want: "out/@dev/index.html"},
+		// The case of the host doesn't matter.
+		{imprt: "Pack.AG/amqp", base: "pack.ag", layout: layoutFile, want: "out/amqp.html"},
+	}
+	for _, tt := range tests {
+		imprt := vanityImport{Import: tt.imprt, Variant: tt.variant}
+		got := filepath.ToSlash(htmlPath(imprt, tt.base, "out", tt.layout))
+		if got != tt.want {
+			t.Errorf("htmlPath(%q, %q, %s) = %q, want %q", tt.imprt, tt.base, tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestConfigParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*config)
+		err      string
+		prefix   string
+		prefixes []string
+		search   []string
+	}{
+		{
+			name:     "minimal",
+			prefix:   "pack.ag",
+			prefixes: []string{"pack.ag"},
+			search:   []string{"vcabbage"},
+		},
+		{
+			name:     "multi-segment prefix",
+			modify:   func(cfg *config) { cfg.prefix = "Example.COM/go/" },
+			prefix:   "example.com/go",
+			prefixes: []string{"example.com/go"},
+			search:   []string{"vcabbage"},
+		},
+		{
+			name:     "several prefixes",
+			modify:   func(cfg *config) { cfg.prefix = "pack.ag, example.com/go ,," },
+			prefix:   "pack.ag",
+			prefixes: []string{"pack.ag", "example.com/go"},
+			search:   []string{"vcabbage"},
+		},
+		{
+			name:     "search list",
+			modify:   func(cfg *config) { cfg.search = " vcabbage, ,other/repo ," },
+			prefix:   "pack.ag",
+			prefixes: []string{"pack.ag"},
+			search:   []string{"vcabbage", "other/repo"},
+		},
+		{name: "no prefix", modify: func(cfg *config) { cfg.prefix = " / ,/" }, err: "must provide vanity URL prefix"},
+		{name: "no search", modify: func(cfg *config) { cfg.search = "" }, err: "search list must contain at least one entry"},
+		{
+			name:   "serve with several prefixes",
+			modify: func(cfg *config) { cfg.prefix, cfg.serve = "pack.ag,example.com", ":8080" },
+			err:    "serve and insecure-serve can't be used with more than one prefix",
+		},
+		{name: "layout", modify: func(cfg *config) { cfg.layout = "tree" }, err: "layout must be file or dir"},
+		{
+			name:   "netlify-mode without netlify",
+			modify: func(cfg *config) { cfg.netlifyMode = netlifyInstead },
+			err:    "netlify-mode instead requires netlify",
+		},
+		{name: "per-page", modify: func(cfg *config) { cfg.perPage = 101 }, err: "per-page must be between 1 and 100"},
+		{name: "concurrency", modify: func(cfg *config) { cfg.concurrency = 0 }, err: "concurrency must be at least 1"},
+		{
+			name:   "resolver without command",
+			modify: func(cfg *config) { cfg.search = resolverPrefix + "pack.ag" },
+			err:    "resolver-command must be provided to search " + resolverPrefix + "pack.ag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config{
+				prefix:      "pack.ag",
+				search:      "vcabbage",
+				perPage:     100,
+				concurrency: 1,
+				retry:       retryPolicy{attempts: 1},
+			}
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+			err := cfg.Parse()
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Parse() = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.prefix != tt.prefix || !reflect.DeepEqual(cfg.prefixes, tt.prefixes) {
+				t.Errorf("prefix = %q, prefixes = %q, want %q, %q", cfg.prefix, cfg.prefixes, tt.prefix, tt.prefixes)
+			}
+			if cfg.prefixURL == nil || cfg.prefixURL.Host+cfg.prefixURL.Path != tt.prefix {
+				t.Errorf("prefixURL = %v, want //%s", cfg.prefixURL, tt.prefix)
+			}
+			if !reflect.DeepEqual(cfg.searchList, tt.search) {
+				t.Errorf("searchList = %q, want %q", cfg.searchList, tt.search)
+			}
+			if cfg.layout != layoutFile || cfg.rootBehavior != rootNone || cfg.netlifyMode != netlifyAlongside {
+				t.Errorf("defaults = %s, %s, %s", cfg.layout, cfg.rootBehavior, cfg.netlifyMode)
+			}
+		})
+	}
+}
+
+func TestGetVanityPackagesNested(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"go.mod":       "module pack.ag/deep\n",
+		"deep.go":      "package deep\n",
+		"a/a.go":       "package a\n",
+		"a/b/c/c.go":   "package c\n",
+		"a/b/c/d/d.go": "package d\n",
+	})
+
+	cfg := testConfig("pack.ag")
+	cfg.moduleOnly = true
+	imports, _, err := getVanityPackages(context.Background(), nil, cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"pack.ag/deep":         0,
+		"pack.ag/deep/a":       1,
+		"pack.ag/deep/a/b/c":   3,
+		"pack.ag/deep/a/b/c/d": 4,
+	}
+	if len(imports) != len(want) {
+		t.Fatalf("found %d imports, want %d", len(imports), len(want))
+	}
+	for _, imprt := range imports {
+		pathLen, ok := want[imprt.Import]
+		if !ok {
+			t.Errorf("unexpected import %s", imprt.Import)
+			continue
+		}
+		if imprt.PathLen != pathLen {
+			t.Errorf("%s: pathLen = %d, want %d", imprt.Import, imprt.PathLen, pathLen)
+		}
+		// Every package resolves to the module root, with {/dir}
+		// filling in its directory.
+		if got := imprt.ImportPrefix(); got != "pack.ag/deep" {
+			t.Errorf("%s: ImportPrefix() = %q, want pack.ag/deep", imprt.Import, got)
+		}
+		if got, want := imprt.SourceDir(), dir+"/tree/master{/dir}"; got != want {
+			t.Errorf("%s: SourceDir() = %q, want %q", imprt.Import, got, want)
+		}
+	}
+}
+
+func TestCheckRootPage(t *testing.T) {
+	root := vanityImport{Import: "pack.ag", RepoURL: "https://github.com/vcabbage/root"}
+	tftp := vanityImport{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp"}
+	tests := []struct {
+		rootBehavior string
+		layout       string
+		pages        []vanityImport
+		ok           bool
+	}{
+		{rootBehavior: rootNone, layout: layoutDir, pages: []vanityImport{root, tftp}, ok: true},
+		{rootBehavior: rootIndex, layout: layoutDir, pages: []vanityImport{tftp}, ok: true},
+		{rootBehavior: rootIndex, layout: layoutDir, pages: []vanityImport{tftp, root}},
+		{rootBehavior: rootIndex, layout: layoutFile, pages: []vanityImport{tftp, root}},
+		{rootBehavior: rootRedirect, layout: layoutDir, pages: []vanityImport{root}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.rootBehavior = tt.rootBehavior
+		cfg.layout = tt.layout
+		if err := checkRootPage(cfg, "out", tt.pages); (err == nil) != tt.ok {
+			t.Errorf("checkRootPage(%s, %s) = %v, want ok %t", tt.rootBehavior, tt.layout, err, tt.ok)
+		}
+	}
+}
+
+func TestEnvBoolDefault(t *testing.T) {
+	tests := []struct {
+		value string
+		def   bool
+		want  bool
+	}{
+		{value: "", def: true, want: true},
+		{value: "", def: false, want: false},
+		{value: "0", def: true, want: false},
+		{value: "1", def: true, want: true},
+		{value: "1", def: false, want: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("GOVANITY_TEST", tt.value)
+		if got := envBoolDefault("GOVANITY_TEST", tt.def); got != tt.want {
+			t.Errorf("envBoolDefault(%q, %t) = %t, want %t", tt.value, tt.def, got, tt.want)
+		}
+	}
+}
+
+// TestCommandPage checks that the page of a command in a module lets
+// go install resolve it: the go command takes the repository from the
+// go-import tag whose prefix is the module path, which the import path
+// of the command is within.
+func TestCommandPage(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"go.mod":           "module pack.ag/tool\n",
+		"tool.go":          "package tool\n",
+		"cmd/tool/main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	for _, commands := range []bool{true, false} {
+		cfg := testConfig("pack.ag")
+		cfg.pageTmpl = vanity.PageTemplate
+		cfg.moduleOnly = true
+		cfg.commands = commands
+		imports, skipped, err := getVanityPackages(context.Background(), nil, cfg, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cmd *vanityImport
+		for i := range imports {
+			if imports[i].Import == "pack.ag/tool/cmd/tool" {
+				cmd = &imports[i]
+			}
+		}
+		if !commands {
+			if cmd != nil || len(skipped) != 1 || skipped[0].Path != "pack.ag/tool/cmd/tool" {
+				t.Errorf("commands=false found %+v, skipped %+v", cmd, skipped)
+			}
+			continue
+		}
+		if cmd == nil {
+			t.Fatalf("no page for the command, skipped %+v", skipped)
+		}
+
+		html, err := cfg.renderPage(context.Background(), *cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta, err := parseMeta(html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		goImport := strings.Fields(meta["go-import"])
+		if len(goImport) != 3 {
+			t.Fatalf("go-import = %q", meta["go-import"])
+		}
+		if root, vcs, repo := goImport[0], goImport[1], goImport[2]; root != "pack.ag/tool" || vcs != "git" || repo != dir {
+			t.Errorf("go-import = %q, want the module root pack.ag/tool at %s", meta["go-import"], dir)
+		}
+		if !strings.HasPrefix(cmd.Import, goImport[0]+"/") {
+			t.Errorf("%s isn't within the go-import root %s", cmd.Import, goImport[0])
+		}
+	}
+}
+
+func TestRepoURLs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{
+  "repos": {
+    "https://github.com/vcabbage/tftp": {"import_url": "https://github.com/vcabbage/tftp.git"},
+    "https://git.example.com/team/amqp/": {
+      "import_url": "ssh://git@git.example.com/team/amqp.git",
+      "source_url": "https://code.example.com/team/amqp/"
+    },
+    "https://gitlab.example.com/team/lab": {"source_url": "https://gitlab.com/team/lab"}
+  }
+}`})
+
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	if err := cfg.load(filepath.Join(dir, "config.json"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		imprt    vanityImport
+		goImport string
+		goSource string
+	}{
+		{
+			imprt:    vanityImport{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+			goImport: "pack.ag/tftp git https://github.com/vcabbage/tftp.git",
+			goSource: "pack.ag/tftp https://github.com/vcabbage/tftp https://github.com/vcabbage/tftp/tree/master{/dir} https://github.com/vcabbage/tftp/blob/master{/dir}/{file}#L{line}",
+		},
+		{
+			imprt:    vanityImport{Import: "pack.ag/amqp", RepoURL: "https://git.example.com/team/amqp", Branch: "main"},
+			goImport: "pack.ag/amqp git ssh://git@git.example.com/team/amqp.git",
+			goSource: "pack.ag/amqp https://code.example.com/team/amqp https://code.example.com/team/amqp/tree/main{/dir} https://code.example.com/team/amqp/blob/main{/dir}/{file}#L{line}",
+		},
+		{
+			// The host of the source URL chooses the layout of its
+			// links.
+			imprt:    vanityImport{Import: "pack.ag/lab", RepoURL: "https://gitlab.example.com/team/lab", Branch: "main"},
+			goImport: "pack.ag/lab git https://gitlab.example.com/team/lab",
+			goSource: "pack.ag/lab https://gitlab.com/team/lab https://gitlab.com/team/lab/-/tree/main{/dir} https://gitlab.com/team/lab/-/blob/main{/dir}/{file}#L{line}",
+		},
+		{
+			imprt:    vanityImport{Import: "pack.ag/other", RepoURL: "https://github.com/vcabbage/other", Branch: "master"},
+			goImport: "pack.ag/other git https://github.com/vcabbage/other",
+			goSource: "pack.ag/other https://github.com/vcabbage/other https://github.com/vcabbage/other/tree/master{/dir} https://github.com/vcabbage/other/blob/master{/dir}/{file}#L{line}",
+		},
+	}
+	for _, tt := range tests {
+		imports := []vanityImport{tt.imprt}
+		cfg.applyImportConfig(imports)
+		html, err := cfg.renderPage(context.Background(), imports[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta, err := parseMeta(html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta["go-import"] != tt.goImport {
+			t.Errorf("%s: go-import = %q, want %q", tt.imprt.Import, meta["go-import"], tt.goImport)
+		}
+		if meta["go-source"] != tt.goSource {
+			t.Errorf("%s: go-source = %q, want %q", tt.imprt.Import, meta["go-source"], tt.goSource)
+		}
+	}
+}
+
+func TestGetVanityPackagesModuleOnly(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"go.mod":               "module pack.ag/tftp\n",
+		"tftp.go":              "package tftp // import \"pack.ag/oldtftp\"\n",
+		"netascii/netascii.go": "package netascii // import \"pack.ag/oldtftp/netascii\"\n",
+	})
+
+	tests := []struct {
+		moduleOnly bool
+		want       []string
+	}{
+		{moduleOnly: false, want: []string{"pack.ag/oldtftp", "pack.ag/oldtftp/netascii"}},
+		{moduleOnly: true, want: []string{"pack.ag/tftp", "pack.ag/tftp/netascii"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.moduleOnly = tt.moduleOnly
+		imports, _, err := getVanityPackages(context.Background(), nil, cfg, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, imprt := range imports {
+			got = append(got, imprt.Import)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("module-only %t: imports = %q, want %q", tt.moduleOnly, got, tt.want)
+		}
+	}
+}
+
+func TestGetVanityPackagesOverrides(t *testing.T) {
+	files := map[string]string{
+		"tftp.go":              "package tftp // import \"github.com/vcabbage/tftp\"\n",
+		"netascii/netascii.go": "package netascii\n",
+		vanity.OverridesFile: `{"packages": [
+			{"import": "pack.ag/tftp", "dir": "."},
+			{"import": "pack.ag/tftp/netascii", "dir": "netascii"},
+			{"import": "github.com/vcabbage/tftp/x", "dir": "x"}
+		]}`,
+	}
+	want := []vanityImport{
+		{Import: "pack.ag/tftp", Branch: "master"},
+		{Import: "pack.ag/tftp/netascii", Branch: "master", PathLen: 1},
+	}
+	wantSkipped := []skippedPackage{{Path: "github.com/vcabbage/tftp/x", Reason: "non-matching prefix"}}
+
+	tests := []struct {
+		name string
+		scan func(t *testing.T) (string, []vanityImport, []skippedPackage, error)
+	}{
+		{
+			name: "clone",
+			scan: func(t *testing.T) (string, []vanityImport, []skippedPackage, error) {
+				dir := t.TempDir()
+				gitInit(t, dir, files)
+				imports, skipped, err := getVanityPackages(context.Background(), nil, testConfig("pack.ag"), dir)
+				return dir, imports, skipped, err
+			},
+		},
+		{
+			name: "api",
+			scan: func(t *testing.T) (string, []vanityImport, []skippedPackage, error) {
+				cfg, gh := fakeContentsAPI(t, files)
+				url := cfg.githubURL + "/vcabbage/tftp"
+				imports, skipped, err := getVanityPackagesAPI(context.Background(), gh, cfg, url)
+				return url, imports, skipped, err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, imports, skipped, err := tt.scan(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range want {
+				want[i].RepoURL = url
+			}
+			if !reflect.DeepEqual(imports, want) {
+				t.Errorf("imports = %+v, want %+v", imports, want)
+			}
+			if !reflect.DeepEqual(skipped, wantSkipped) {
+				t.Errorf("skipped = %+v, want %+v", skipped, wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCollectionPages(t *testing.T) {
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.rootBehavior = rootNone
+	cfg.collections = []collection{
+		{Name: "protocols", Title: "Network protocols", Imports: []string{"pack.ag/tftp", "pack.ag/amqp"}},
+		{Name: "tools", Imports: []string{"pack.ag/cmd/govanity"}},
+	}
+	dir := t.TempDir()
+	imports := []vanityImport{
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		{Import: "pack.ag/cmd/govanity", RepoURL: "https://github.com/vcabbage/govanity", Branch: "master", PathLen: 1},
+	}
+	if _, err := generate(context.Background(), cfg, dir, imports); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		title   string
+		members []string // in order
+		others  []string
+	}{
+		{name: "protocols", title: "Network protocols", members: []string{"pack.ag/amqp", "pack.ag/tftp"}, others: []string{"pack.ag/cmd/govanity"}},
+		{name: "tools", title: "tools", members: []string{"pack.ag/cmd/govanity"}, others: []string{"pack.ag/amqp", "pack.ag/tftp"}},
+	}
+	for _, tt := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "collections", tt.name+".html"))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		page := string(data)
+		if !strings.Contains(page, "<title>"+tt.title+"</title>") {
+			t.Errorf("%s: title isn't %q:\n%s", tt.name, tt.title, page)
+		}
+		last := -1
+		for _, member := range tt.members {
+			i := strings.Index(page, `<a href="https://`+member+`">`)
+			if i < 0 || i < last {
+				t.Errorf("%s: %s is missing or out of order:\n%s", tt.name, member, page)
+			}
+			last = i
+		}
+		for _, other := range tt.others {
+			if strings.Contains(page, other) {
+				t.Errorf("%s: lists %s of another collection:\n%s", tt.name, other, page)
+			}
+		}
+	}
+}
+
+func TestCloneRewrite(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	mirror := t.TempDir()
+	gitInit(t, filepath.Join(mirror, "vcabbage", "tftp"), map[string]string{
+		"go.mod":  "module pack.ag/tftp\n",
+		"tftp.go": "package tftp\n",
+	})
+
+	// The repository is only reachable through the mirror.
+	cfg := testConfig("pack.ag")
+	cfg.moduleOnly = true
+	cfg.cloneRewrite = regexp.MustCompile(`^https://github\.com/`)
+	cfg.cloneReplace = mirror + "/"
+	const repoURL = "https://github.com/vcabbage/tftp"
+	imports, _, err := getVanityPackages(context.Background(), nil, cfg, repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 1 {
+		t.Fatalf("found %d imports, want 1", len(imports))
+	}
+
+	var buf bytes.Buffer
+	if err := imports[0].RenderHTML(&buf, vanity.RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := parseMeta(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"go-import": "pack.ag/tftp git " + repoURL,
+		"go-source": "pack.ag/tftp " + repoURL + " " + repoURL + "/tree/master{/dir} " + repoURL + "/blob/master{/dir}/{file}#L{line}",
+	}
+	for name, want := range want {
+		if got := meta[name]; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGetVanityPackagesPinnedCommit(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir := t.TempDir()
+	gitInit(t, dir, map[string]string{
+		"go.mod":  "module pack.ag/tftp\n",
+		"tftp.go": "package tftp\n",
+	})
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := strings.TrimSpace(string(out))
+	// The branch moves on after the commit is pinned.
+	writeTestFiles(t, dir, map[string]string{"netascii/netascii.go": "package netascii\n"})
+	gitCommit(t, dir)
+
+	cfg := testConfig("pack.ag")
+	cfg.moduleOnly = true
+	cfg.repos = map[string]repoConfig{dir: {Commit: sha}}
+	imports, _, err := getVanityPackages(context.Background(), nil, cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []vanityImport{{Import: "pack.ag/tftp", RepoURL: dir, Branch: sha, File: "tftp.go"}}
+	if !reflect.DeepEqual(imports, want) {
+		t.Errorf("imports = %+v, want %+v", imports, want)
+	}
+}
+
+func TestWriteRoot(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+	}
+	tests := []struct {
+		behavior string
+		redirect string
+		want     []string // in order, nil if no index is written
+	}{
+		{
+			behavior: rootIndex,
+			want:     []string{"<title>pack.ag</title>", `<a href="https://pack.ag/amqp">`, `<a href="https://pack.ag/tftp">`},
+		},
+		{
+			behavior: rootRedirect,
+			redirect: "https://github.com/vcabbage",
+			want:     []string{`<meta http-equiv="refresh" content="0; url=https://github.com/vcabbage">`},
+		},
+		{behavior: rootNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.behavior, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.rootBehavior = tt.behavior
+			cfg.rootRedirect = tt.redirect
+			dir := t.TempDir()
+			if err := writeRoot(context.Background(), &siteWriter{dir: dir}, cfg, imports); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("index.html was written:\n%s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, last := string(data), -1
+			for _, want := range tt.want {
+				i := strings.Index(page, want)
+				if i < 0 || i < last {
+					t.Errorf("%s is missing or out of order:\n%s", want, page)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func TestGroupByTag(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+		{Import: "pack.ag/sctp", RepoURL: "https://github.com/vcabbage/sctp", Branch: "master"},
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+	}
+	tests := []struct {
+		name    string
+		imports map[string]importConfig
+		want    []string // in order
+	}{
+		{
+			name: "tagged",
+			imports: map[string]importConfig{
+				"pack.ag/amqp": {Tags: []string{"messaging"}},
+				"pack.ag/tftp": {Tags: []string{"networking", "messaging"}},
+			},
+			want: []string{
+				`<h2 id="messaging">messaging</h2>`, `<a href="https://pack.ag/amqp">`, `<a href="https://pack.ag/tftp">`,
+				`<h2 id="networking">networking</h2>`, `<a href="https://pack.ag/tftp">`,
+				`<h2 id="Other">Other</h2>`, `<a href="https://pack.ag/sctp">`,
+			},
+		},
+		{
+			name: "untagged",
+			want: []string{`<h1>pack.ag</h1>`, `<a href="https://pack.ag/amqp">`, `<a href="https://pack.ag/sctp">`, `<a href="https://pack.ag/tftp">`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.rootBehavior = rootIndex
+			cfg.imports = tt.imports
+			imports := append([]vanityImport(nil), imports...)
+			cfg.applyImportConfig(imports)
+
+			dir := t.TempDir()
+			if err := writeRoot(context.Background(), &siteWriter{dir: dir}, cfg, imports); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			page, last := string(data), 0
+			for _, want := range tt.want {
+				i := strings.Index(page[last:], want)
+				if i < 0 {
+					t.Fatalf("%s is missing or out of order:\n%s", want, page)
+				}
+				last += i + len(want)
+			}
+			if tt.imports == nil && strings.Contains(page, "<h2") {
+				t.Errorf("untagged imports are grouped:\n%s", page)
+			}
+		})
+	}
+}
+
+func TestRobotsTxt(t *testing.T) {
+	tests := []struct {
+		robots string
+		want   string // empty if no robots.txt is written
+	}{
+		{robots: robotsAllow, want: "User-agent: *\nDisallow:\n"},
+		{robots: robotsDisallow, want: "User-agent: *\nDisallow: /\n"},
+		{robots: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.robots, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.pageTmpl = vanity.PageTemplate
+			cfg.rootBehavior = rootNone
+			cfg.robots = tt.robots
+			dir := t.TempDir()
+			imports := []vanityImport{
+				{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+			}
+			if _, err := generate(context.Background(), cfg, dir, imports); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "robots.txt"))
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("robots.txt was written:\n%s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("robots.txt = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
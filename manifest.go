@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// manifestVersion is the version of the format written to -manifest,
+// incremented when fields are changed or removed.
+const manifestVersion = 1
+
+// manifest is the format of the file written to -manifest.
+type manifest struct {
+	Version int              `json:"version"`
+	Imports []manifestImport `json:"imports"`
+}
+
+// manifestImport describes the page generated for an import.
+type manifestImport struct {
+	Import       string `json:"import"`
+	ImportPrefix string `json:"import_prefix"`
+	RepoURL      string `json:"repo_url"`
+	Branch       string `json:"branch,omitempty"`
+	Page         string `json:"page"` // relative to the output directory
+}
+
+// writeManifest writes a JSON description of the page of each import to
+// path, so that other tools can use the imports found without scanning.
+func writeManifest(cfg *config, path string, imports []vanityImport) error {
+	m := manifest{Version: manifestVersion, Imports: []manifestImport{}}
+	for _, imprt := range imports {
+		m.Imports = append(m.Imports, manifestImport{
+			Import:       imprt.Import,
+			ImportPrefix: imprt.ImportPrefix(),
+			RepoURL:      imprt.RepoURL,
+			Branch:       imprt.Branch,
+			Page:         filepath.ToSlash(cfg.pagePath(imprt, ".")),
+		})
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// readMappings reads explicit vanity imports from the file at path.
+//
+// Each line contains an import path, the repository URL, and optionally the
+// branch used for go-source links, separated by whitespace. Blank lines and
+// lines beginning with # are ignored.
+//
+// Example:
+//
+//	# import       repository                           branch
+//	pack.ag/tftp   https://github.com/vcabbage/go-tftp  master
+func readMappings(cfg *config, path string) ([]vanityImport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var imports []vanityImport
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			fields[0] = vanity.CleanImportPath(fields[0])
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected import path, repository URL, and optional branch", path, lineNum)
+		}
+		if cfg.matchPrefix(fields[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: %s does not match prefix %s", path, lineNum, fields[0], strings.Join(cfg.prefixes, ","))
+		}
+
+		imprt := vanityImport{
+			Import:  fields[0],
+			RepoURL: strings.TrimSuffix(fields[1], "/"),
+		}
+		if len(fields) == 3 {
+			imprt.Branch = fields[2]
+		}
+		imports = append(imports, imprt)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []vanityImport
+		wantErr string
+	}{
+		{
+			name: "mappings",
+			data: "# import       repository                           branch\n" +
+				"pack.ag/tftp   https://github.com/vcabbage/go-tftp  master\n" +
+				"\n" +
+				"  Pack.AG/amqp/ https://github.com/vcabbage/amqp/\n",
+			want: []vanityImport{
+				{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/go-tftp", Branch: "master"},
+				{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp"},
+			},
+		},
+		{
+			name:    "missing repository",
+			data:    "pack.ag/tftp\n",
+			wantErr: "mappings:1: expected import path, repository URL, and optional branch",
+		},
+		{
+			name:    "extra field",
+			data:    "# tftp\npack.ag/tftp https://github.com/vcabbage/go-tftp master v2\n",
+			wantErr: "mappings:2: expected import path, repository URL, and optional branch",
+		},
+		{
+			name:    "other prefix",
+			data:    "github.com/vcabbage/tftp https://github.com/vcabbage/go-tftp\n",
+			wantErr: "mappings:1: github.com/vcabbage/tftp does not match prefix pack.ag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, map[string]string{"mappings": tt.data})
+			got, err := readMappings(testConfig("pack.ag"), filepath.Join(dir, "mappings"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Fatalf("readMappings() = %v, want error ending %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readMappings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runStats summarizes a run for the metrics file and the summary.
+type runStats struct {
+	start    time.Time
+	repos    int
+	matched  int // repositories with at least one package
+	empty    int // repositories without any packages
+	packages int
+	pages    int // packages given pages, after duplicates and exclusions are dropped
+	errors   int
+}
+
+// addResults counts the repositories, packages, and failed
+// repositories in results. Each repository is counted as failed, with
+// packages, or empty, but only one of them.
+func (s *runStats) addResults(results []repoResult) {
+	for _, result := range results {
+		s.repos++
+		s.packages += len(result.imports)
+		switch {
+		case result.err != nil:
+			s.errors++
+		case len(result.imports) > 0:
+			s.matched++
+		default:
+			s.empty++
+		}
+	}
+}
+
+// addImports counts the packages in imports and the distinct
+// repositories they belong to.
+func (s *runStats) addImports(imports []vanityImport) {
+	repos := make(map[string]struct{})
+	for _, imprt := range imports {
+		repos[imprt.RepoURL] = struct{}{}
+	}
+	s.repos += len(repos)
+	s.matched += len(repos)
+	s.packages += len(imports)
+}
+
+// summarize logs a summary of the run.
+func (s *runStats) summarize() {
+	logf("Scanned %d repositories: %d with packages, %d with none, %d failed. Found %d packages, %d with pages.\n",
+		s.repos, s.matched, s.empty, s.errors, s.packages, s.pages)
+}
+
+const lastSuccessMetric = "govanity_last_success_timestamp"
+
+// writeMetrics writes stats to path in the Prometheus text format, suitable
+// for the node exporter textfile collector. When the run failed the
+// last success timestamp is carried over from the existing file.
+func writeMetrics(path string, stats *runStats, runErr error, now time.Time) error {
+	errCount := stats.errors
+	var lastSuccess float64
+	if runErr == nil {
+		lastSuccess = float64(now.Unix())
+	} else {
+		errCount++
+		lastSuccess = previousMetric(path, lastSuccessMetric)
+	}
+
+	var buf bytes.Buffer
+	metric := func(name, typ, help string, value float64) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, typ)
+		fmt.Fprintf(&buf, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	metric("govanity_repos_total", "gauge", "Repositories scanned by the last run.", float64(stats.repos))
+	metric("govanity_packages_total", "gauge", "Vanity imports found by the last run.", float64(stats.packages))
+	metric("govanity_errors_total", "gauge", "Errors encountered by the last run.", float64(errCount))
+	metric("govanity_duration_seconds", "gauge", "Duration of the last run.", now.Sub(stats.start).Seconds())
+	metric(lastSuccessMetric, "gauge", "Unix time of the last successful run.", lastSuccess)
+
+	// Write to a temporary file and rename it so the collector
+	// never reads a partially written file.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".govanity-metrics")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// previousMetric returns the value of name in the metrics file at path,
+// or zero if it can't be read.
+func previousMetric(path, name string) float64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != name {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0
+		}
+		return value
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunStatsAddResults(t *testing.T) {
+	pkg := vanityImport{Import: "pack.ag/tftp"}
+	tests := []struct {
+		name    string
+		results []repoResult
+		want    runStats
+	}{
+		{
+			name: "each kind",
+			results: []repoResult{
+				{url: "a", imports: []vanityImport{pkg, pkg}},
+				{url: "b"},
+				{url: "c", err: errors.New("clone failed")},
+			},
+			want: runStats{repos: 3, matched: 1, empty: 1, errors: 1, packages: 2},
+		},
+		{
+			// A repository which failed part way through is only
+			// counted as failed, but its packages are still found.
+			name: "failed with packages",
+			results: []repoResult{
+				{url: "a", imports: []vanityImport{pkg}, err: errors.New("listing v2 failed")},
+			},
+			want: runStats{repos: 1, errors: 1, packages: 1},
+		},
+		{
+			name:    "all empty",
+			results: []repoResult{{url: "a"}, {url: "b"}},
+			want:    runStats{repos: 2, empty: 2},
+		},
+	}
+	for _, tt := range tests {
+		var got runStats
+		got.addResults(tt.results)
+		if got != tt.want {
+			t.Errorf("%s: stats = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got.matched+got.empty+got.errors != got.repos {
+			t.Errorf("%s: categories %d + %d + %d don't add up to %d repositories", tt.name, got.matched, got.empty, got.errors, got.repos)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	stats := &runStats{start: start, repos: 3, packages: 5, errors: 1}
+	path := filepath.Join(t.TempDir(), "govanity.prom")
+
+	tests := []struct {
+		name   string
+		runErr error
+		now    time.Time
+		want   string
+	}{
+		{
+			name: "success",
+			now:  start.Add(90 * time.Second),
+			want: `# HELP govanity_repos_total Repositories scanned by the last run.
+# TYPE govanity_repos_total gauge
+govanity_repos_total 3
+# HELP govanity_packages_total Vanity imports found by the last run.
+# TYPE govanity_packages_total gauge
+govanity_packages_total 5
+# HELP govanity_errors_total Errors encountered by the last run.
+# TYPE govanity_errors_total gauge
+govanity_errors_total 1
+# HELP govanity_duration_seconds Duration of the last run.
+# TYPE govanity_duration_seconds gauge
+govanity_duration_seconds 90
+# HELP govanity_last_success_timestamp Unix time of the last successful run.
+# TYPE govanity_last_success_timestamp gauge
+govanity_last_success_timestamp 1700000090
+`,
+		},
+		{
+			// The last success is carried over from the first run.
+			name:   "failure",
+			runErr: errors.New("aborting after 2 failed repositories"),
+			now:    start.Add(2500 * time.Millisecond),
+			want: `# HELP govanity_repos_total Repositories scanned by the last run.
+# TYPE govanity_repos_total gauge
+govanity_repos_total 3
+# HELP govanity_packages_total Vanity imports found by the last run.
+# TYPE govanity_packages_total gauge
+govanity_packages_total 5
+# HELP govanity_errors_total Errors encountered by the last run.
+# TYPE govanity_errors_total gauge
+govanity_errors_total 2
+# HELP govanity_duration_seconds Duration of the last run.
+# TYPE govanity_duration_seconds gauge
+govanity_duration_seconds 2.5
+# HELP govanity_last_success_timestamp Unix time of the last successful run.
+# TYPE govanity_last_success_timestamp gauge
+govanity_last_success_timestamp 1700000090
+`,
+		},
+	}
+	for _, tt := range tests {
+		if err := writeMetrics(path, stats, tt.runErr, tt.now); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: metrics =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// readModuleList reads the output of go list -m all from the file at path,
+// or stdin if path is "-", returning the version of each module matching
+// one of cfg.prefixes.
+//
+// Example:
+//
+//	example.com/app
+//	pack.ag/amqp v0.12.5
+//	pack.ag/tftp v1.0.1-0.20190201042838-45cd8db7bd5f
+//	pack.ag/x v2.0.0+incompatible
+func readModuleList(cfg *config, path string) (map[string]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	modules := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// The main module and modules replaced for all versions
+		// don't have a version. Otherwise the required version comes
+		// before any replacement.
+		if len(fields) < 2 || fields[1] == "=>" {
+			continue
+		}
+		if !strings.HasPrefix(fields[1], "v") {
+			return nil, fmt.Errorf("%s:%d: invalid version %q", path, lineNum, fields[1])
+		}
+		if cfg.inModulePrefix(fields[0]) {
+			modules[fields[0]] = fields[1]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// pseudoVersionRE matches the timestamp and commit of a pseudo-version.
+var pseudoVersionRE = regexp.MustCompile(`[-.][0-9]{14}-([0-9a-f]{12})$`)
+
+// versionRef returns the git ref of version for a module in subdir of its
+// repository: the commit of a pseudo-version, otherwise the release tag.
+func versionRef(version, subdir string) string {
+	version = strings.TrimSuffix(version, "+incompatible")
+	if m := pseudoVersionRE.FindStringSubmatch(version); m != nil {
+		return m[1]
+	}
+	if subdir != "" {
+		return subdir + "/" + version
+	}
+	return version
+}
+
+// pinModules returns the imports belonging to modules, with source links
+// pointing at the listed version of their module.
+func pinModules(imports []vanityImport, modules map[string]string) []vanityImport {
+	var pinned []vanityImport
+	for _, imprt := range imports {
+		module := ""
+		for m := range modules {
+			if (imprt.Import == m || strings.HasPrefix(imprt.Import, m+"/")) && len(m) > len(module) {
+				module = m
+			}
+		}
+		if module == "" {
+			continue
+		}
+
+		imprt.Branch = versionRef(modules[module], moduleSubdir(module, imprt.ImportPrefix()))
+		pinned = append(pinned, imprt)
+	}
+	return pinned
+}
+
+// moduleSubdir returns the tag prefix of module within the repository at
+// repoRoot. Major version subdirectories aren't part of the tag.
+func moduleSubdir(module, repoRoot string) string {
+	subdir := strings.Trim(strings.TrimPrefix(module, repoRoot), "/")
+	if i := strings.LastIndex(subdir, "/"); majorBranchRE.MatchString(subdir[i+1:]) {
+		subdir = subdir[:i+1]
+	}
+	return strings.TrimSuffix(subdir, "/")
+}
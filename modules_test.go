@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPinModules(t *testing.T) {
+	// The output of go list -m all in a module depending on pack.ag
+	// modules, with replacements.
+	const list = `example.com/app
+github.com/google/go-github v17.0.0+incompatible
+pack.ag/amqp v0.12.5
+pack.ag/tftp v1.0.1-0.20190201042838-45cd8db7bd5f
+pack.ag/sctp/v2 v2.1.0
+pack.ag/x v2.0.0+incompatible => ../x
+pack.ag/local => ../local
+`
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"modules.txt": list})
+	modules, err := readModuleList(testConfig("pack.ag"), filepath.Join(dir, "modules.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantModules := map[string]string{
+		"pack.ag/amqp":    "v0.12.5",
+		"pack.ag/tftp":    "v1.0.1-0.20190201042838-45cd8db7bd5f",
+		"pack.ag/sctp/v2": "v2.1.0",
+		"pack.ag/x":       "v2.0.0+incompatible",
+	}
+	if !reflect.DeepEqual(modules, wantModules) {
+		t.Fatalf("readModuleList() = %v, want %v", modules, wantModules)
+	}
+
+	imports := []vanityImport{
+		{Import: "pack.ag/amqp", Branch: "master"},
+		{Import: "pack.ag/amqp/internal/encoding", Branch: "master", PathLen: 2},
+		{Import: "pack.ag/tftp/netascii", Branch: "master", PathLen: 1},
+		{Import: "pack.ag/sctp/v2", Branch: "master", PathLen: 1},
+		{Import: "pack.ag/x", Branch: "master"},
+		{Import: "pack.ag/unused", Branch: "master"},
+	}
+	var got []string
+	for _, imprt := range pinModules(imports, modules) {
+		got = append(got, imprt.Import+" "+imprt.Branch)
+	}
+	want := []string{
+		"pack.ag/amqp v0.12.5",
+		"pack.ag/amqp/internal/encoding v0.12.5",
+		"pack.ag/tftp/netascii 45cd8db7bd5f",
+		"pack.ag/sctp/v2 v2.1.0",
+		"pack.ag/x v2.0.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pinModules() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path"
+	"sort"
+)
+
+// addMoved returns imports with a page for each import path configured as
+// moved to another import found in imports. The page's go-import points at
+// the repository of the new import so existing code keeps building, any
+// page previously generated for the old path is replaced.
+func addMoved(imports []vanityImport, configs map[string]importConfig) []vanityImport {
+	byPath := make(map[string]vanityImport, len(imports))
+	for _, imprt := range imports {
+		byPath[imprt.Import] = imprt
+	}
+
+	var moved []string
+	for old, ic := range configs {
+		if ic.MovedTo == "" {
+			continue
+		}
+		to, ok := byPath[ic.MovedTo]
+		if !ok {
+			warnf("", "%s: moved to %s, which wasn't found", old, ic.MovedTo)
+			continue
+		}
+
+		byPath[old] = vanityImport{
+			Import:        old,
+			RepoURL:       to.RepoURL,
+			Branch:        to.Branch,
+			Synopsis:      to.Synopsis,
+			MovedTo:       to.Import,
+			Subdir:        path.Join(to.Subdir, to.Dir()),
+			Command:       to.Command,
+			RedirectMoved: ic.Redirect,
+			GoImportURL:   to.GoImportURL,
+			GoSourceURL:   to.GoSourceURL,
+			GoImportVCS:   to.GoImportVCS,
+			GitLab:        to.GitLab,
+			SourceFormat:  to.SourceFormat,
+		}
+		moved = append(moved, old)
+	}
+	if len(moved) == 0 {
+		return imports
+	}
+	sort.Strings(moved)
+
+	var result []vanityImport
+	for _, imprt := range imports {
+		if byPath[imprt.Import].MovedTo == "" {
+			result = append(result, imprt)
+		}
+	}
+	for _, old := range moved {
+		result = append(result, byPath[old])
+	}
+	return result
+}
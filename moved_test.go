@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestAddMoved(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp/v2", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master", PathLen: 1},
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+	}
+	tests := []struct {
+		name    string
+		configs map[string]importConfig
+		paths   []string // of the imports returned
+		want    []string // in the page of pack.ag/tftp
+	}{
+		{
+			name:    "moved",
+			configs: map[string]importConfig{"pack.ag/tftp": {MovedTo: "pack.ag/tftp/v2"}},
+			paths:   []string{"pack.ag/tftp/v2", "pack.ag/amqp", "pack.ag/tftp"},
+			want: []string{
+				`<meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp v2">`,
+				`<meta http-equiv="refresh" content="0; url=https://github.com/vcabbage/tftp">`,
+				`<p>This package has moved to <a href="https://pack.ag/tftp/v2">pack.ag/tftp/v2</a>.</p>`,
+			},
+		},
+		{
+			name:    "redirect",
+			configs: map[string]importConfig{"pack.ag/tftp": {MovedTo: "pack.ag/tftp/v2", Redirect: true}},
+			paths:   []string{"pack.ag/tftp/v2", "pack.ag/amqp", "pack.ag/tftp"},
+			want: []string{
+				`<meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp v2">`,
+				`<meta http-equiv="refresh" content="0; url=https://pack.ag/tftp/v2">`,
+				`<p>This package has moved to <a href="https://pack.ag/tftp/v2">pack.ag/tftp/v2</a>.</p>`,
+			},
+		},
+		{
+			name:    "not found",
+			configs: map[string]importConfig{"pack.ag/tftp": {MovedTo: "pack.ag/sctp"}},
+			paths:   []string{"pack.ag/tftp/v2", "pack.ag/amqp"},
+		},
+		{
+			name:    "replaces the old page",
+			configs: map[string]importConfig{"pack.ag/amqp": {MovedTo: "pack.ag/tftp/v2"}},
+			paths:   []string{"pack.ag/tftp/v2", "pack.ag/amqp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := addMoved(imports, tt.configs)
+
+			var paths []string
+			for _, imprt := range got {
+				paths = append(paths, imprt.Import)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Fatalf("imports = %q, want %q", paths, tt.paths)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			cfg := testConfig("pack.ag")
+			cfg.pageTmpl = vanity.PageTemplate
+			page, err := cfg.renderPage(context.Background(), got[len(got)-1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(page), want) {
+					t.Errorf("page is missing %s:\n%s", want, page)
+				}
+			}
+		})
+	}
+}
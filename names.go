@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// nameImport returns the vanity import of the repository at url when its
+// owner is configured to derive imports from repository names. The
+// repository isn't cloned, so import comments aren't checked. The import
+// is under the first -prefix.
+func nameImport(ctx context.Context, cfg *config, url, name string) ([]vanityImport, error) {
+	branch := cfg.repos[url].Commit
+	if branch == "" {
+		var err error
+		branch, err = cfg.defaultBranches.get(url, func() (string, error) {
+			return remoteHeadBranch(ctx, cfg, cfg.cloneURL(url))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("finding default branch: %v", err)
+		}
+	}
+
+	return []vanityImport{{
+		Import:  path.Join(cfg.prefix, name),
+		RepoURL: url,
+		Branch:  branch,
+	}}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestNameImports(t *testing.T) {
+	tftp := testRepo("vcabbage", "tftp", false, false)
+	amqp := testRepo("vcabbage", "amqp", false, false)
+	amqp.DefaultBranch = nil // left to the pinned commit
+	tool := testRepo("vcabbage", "cmd-tool", false, false)
+	tool.DefaultBranch = github.String("main")
+	gh := &fakeLister{
+		users: map[string][]*repository{
+			"vcabbage": {tftp, amqp, tool, testRepo("vcabbage", "site", false, false)},
+		},
+		languages: map[string]map[string]int{
+			"vcabbage/tftp":     {"Go": 100},
+			"vcabbage/amqp":     {"Go": 100},
+			"vcabbage/cmd-tool": {"Go": 100},
+			"vcabbage/site":     {"HTML": 100},
+		},
+	}
+
+	cfg := testConfig("pack.ag")
+	cfg.githubURL = defaultGitHubURL
+	cfg.perPage = 100
+	cfg.searchList = []string{"vcabbage"}
+	cfg.orgs = map[string]orgConfig{"vcabbage": {Names: true}}
+	cfg.repos = map[string]repoConfig{"https://github.com/vcabbage/amqp": {Commit: "0123abc"}}
+	repoURLs, err := getPotentialRepos(context.Background(), gh, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type found struct {
+		Import, RepoURL, Branch, ImportPrefix string
+	}
+	var got []found
+	for _, url := range repoURLs {
+		// The repositories aren't cloned, so a nil client is fine.
+		result := scanRepository(context.Background(), nil, cfg, nil, url)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		for _, imprt := range result.imports {
+			got = append(got, found{imprt.Import, imprt.RepoURL, imprt.Branch, imprt.ImportPrefix()})
+		}
+	}
+	want := []found{
+		{"pack.ag/tftp", "https://github.com/vcabbage/tftp", "master", "pack.ag/tftp"},
+		{"pack.ag/amqp", "https://github.com/vcabbage/amqp", "0123abc", "pack.ag/amqp"},
+		{"pack.ag/cmd-tool", "https://github.com/vcabbage/cmd-tool", "main", "pack.ag/cmd-tool"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imports = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// Supported -netlify-mode values.
+const (
+	netlifyAlongside = "alongside" // package pages are written too
+	netlifyInstead   = "instead"   // only _redirects is written
+)
+
+// netlifyRedirects returns the contents of a Netlify _redirects file
+// sending visitors of each page straight to its Home with a 302.
+//
+// Alongside the pages, requests from the go command, which have go-get=1
+// set, are still served the page since it's the only way to provide the
+// meta tags. Both rules are forced so they apply even though the page
+// exists. Instead of the pages, every request is redirected.
+func netlifyRedirects(base, layout, mode string, imports []vanityImport) []byte {
+	var buf bytes.Buffer
+	for _, imprt := range imports {
+		p := urlPath(imprt, base)
+		if p == "/" {
+			// The root is left to -root-behavior.
+			continue
+		}
+		if mode == netlifyInstead {
+			fmt.Fprintf(&buf, "%s %s 302\n", p, imprt.Home())
+			continue
+		}
+		page := filepath.ToSlash(htmlPath(imprt, base, "/", layout))
+		fmt.Fprintf(&buf, "%s go-get=1 %s 200!\n", p, page)
+		fmt.Fprintf(&buf, "%s %s 302!\n", p, imprt.Home())
+	}
+	return buf.Bytes()
+}
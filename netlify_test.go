@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestNetlifyRedirects(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag", RepoURL: "https://github.com/vcabbage/root"},
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp"},
+		{Import: "pack.ag/amqp/internal", RepoURL: "https://github.com/vcabbage/amqp", Redirect: "https://pack.ag/docs"},
+	}
+
+	tests := []struct {
+		layout string
+		mode   string
+		want   string
+	}{
+		{
+			layout: layoutFile,
+			mode:   netlifyAlongside,
+			want: "/tftp go-get=1 /tftp.html 200!\n" +
+				"/tftp https://github.com/vcabbage/tftp 302!\n" +
+				"/amqp/internal go-get=1 /amqp/internal.html 200!\n" +
+				"/amqp/internal https://pack.ag/docs 302!\n",
+		},
+		{
+			layout: layoutDir,
+			mode:   netlifyAlongside,
+			want: "/tftp go-get=1 /tftp/index.html 200!\n" +
+				"/tftp https://github.com/vcabbage/tftp 302!\n" +
+				"/amqp/internal go-get=1 /amqp/internal/index.html 200!\n" +
+				"/amqp/internal https://pack.ag/docs 302!\n",
+		},
+		{
+			layout: layoutFile,
+			mode:   netlifyInstead,
+			want: "/tftp https://github.com/vcabbage/tftp 302\n" +
+				"/amqp/internal https://pack.ag/docs 302\n",
+		},
+		{
+			layout: layoutDir,
+			mode:   netlifyInstead,
+			want: "/tftp https://github.com/vcabbage/tftp 302\n" +
+				"/amqp/internal https://pack.ag/docs 302\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := string(netlifyRedirects("pack.ag", tt.layout, tt.mode, imports)); got != tt.want {
+			t.Errorf("netlifyRedirects(%s, %s) =\n%s\nwant\n%s", tt.layout, tt.mode, got, tt.want)
+		}
+	}
+}
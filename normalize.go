@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// normalize re-renders every page previously generated under cfg.out so
+// that it matches the output of the current template byte for byte.
+// Files which weren't generated by govanity are left untouched.
+func normalize(ctx context.Context, cfg *config) error {
+	var changed, failed int
+	err := filepath.Walk(cfg.out, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		meta, err := parseMeta(data)
+		if err != nil {
+			warnf("", "%s: %v", path, err)
+			failed++
+			return nil
+		}
+		if meta["go-import"] == "" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(cfg.out, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, ".html"))
+		if name == "index" {
+			// The page of the import at the prefix itself.
+			name = ""
+		}
+		if cfg.layout == layoutDir {
+			name = strings.TrimSuffix(name, "/index")
+		}
+		var variant string
+		if i := strings.LastIndex(name, "@"); i >= 0 {
+			name, variant = name[:i], name[i+1:]
+		}
+		imprt, err := pageImport(pageImportPath(cfg.prefix, name, meta), meta)
+		if err != nil {
+			warnf("", "%s: %v", path, err)
+			failed++
+			return nil
+		}
+		imprt.Variant = variant
+		imprt.SourceFormat = cfg.sourceTmpl
+		if imprt.Redirect, err = cfg.redirectURL(imprt); err != nil {
+			return err
+		}
+
+		rendered, err := cfg.renderPage(ctx, imprt)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(rendered, data) {
+			return nil
+		}
+
+		logf("Normalizing %s\n", path)
+		changed++
+		return ioutil.WriteFile(path, rendered, info.Mode())
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("Normalized %d files.\n", changed)
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be normalized", failed)
+	}
+	return nil
+}
+
+// parseMeta returns the content of each named meta tag in an HTML document.
+func parseMeta(data []byte) (map[string]string, error) {
+	meta := make(map[string]string)
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return meta, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data != "meta" {
+				continue
+			}
+
+			var name, content string
+			for _, attr := range tok.Attr {
+				switch attr.Key {
+				case "name", "property":
+					name = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if name != "" {
+				meta[name] = content
+			}
+		}
+	}
+}
+
+// pageImportPath returns the import path of the page at the path name
+// within the site of prefix. The go-import prefix of the page replaces
+// the part of the path it covers, which keeps its case if it was matched
+// with -case-insensitive.
+func pageImportPath(prefix, name string, meta map[string]string) string {
+	importPath := strings.TrimSuffix(prefix+"/"+name, "/")
+	goImport := strings.Fields(meta["go-import"])
+	if len(goImport) == 0 {
+		return importPath
+	}
+	root := goImport[0]
+	if vanity.HasImportPrefix(importPath, root, true) && (len(importPath) == len(root) || importPath[len(root)] == '/') {
+		return root + importPath[len(root):]
+	}
+	return importPath
+}
+
+// pageImport reconstructs the vanityImport for the import path importPath
+// from the meta tags of its generated page.
+func pageImport(importPath string, meta map[string]string) (vanityImport, error) {
+	goImport := strings.Fields(meta["go-import"])
+	if len(goImport) != 3 && len(goImport) != 4 {
+		return vanityImport{}, fmt.Errorf("malformed go-import %q", meta["go-import"])
+	}
+
+	prefix, vcs, repoURL := goImport[0], goImport[1], goImport[2]
+	if importPath != prefix && !strings.HasPrefix(importPath, prefix+"/") {
+		return vanityImport{}, fmt.Errorf("go-import prefix %s does not match %s", prefix, importPath)
+	}
+
+	imprt := vanityImport{
+		Import:   importPath,
+		RepoURL:  repoURL,
+		Synopsis: meta["og:description"],
+		PathLen:  len(strings.Split(importPath, "/")) - len(strings.Split(prefix, "/")),
+	}
+	if vcs != "git" {
+		imprt.GoImportVCS = vcs
+	}
+	if len(goImport) == 4 {
+		imprt.Subdir = goImport[3]
+	}
+	if imprt.Synopsis == importPath {
+		imprt.Synopsis = ""
+	}
+
+	if goSource := strings.Fields(meta["go-source"]); len(goSource) == 4 {
+		// The home may be a -redirect rather than where the
+		// source is browsed.
+		if home := strings.TrimSuffix(goSource[1], "/"); home != repoURL && strings.HasPrefix(goSource[2], home+"/") {
+			imprt.GoSourceURL = home
+		}
+		imprt.GitLab = strings.HasPrefix(goSource[2], imprt.WebURL()+"/-/tree/")
+		tree, _ := imprt.SourcePaths()
+		dir := strings.TrimPrefix(goSource[2], imprt.WebURL()+tree)
+		imprt.Branch = strings.TrimSuffix(strings.TrimSuffix(dir, "{/dir}"), imprt.SubdirPath())
+	}
+	return imprt, nil
+}
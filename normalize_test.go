@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestNormalizeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		layout    string
+		openGraph bool
+		imports   []vanityImport
+	}{
+		{
+			name:   "file",
+			prefix: "pack.ag",
+			layout: layoutFile,
+			imports: []vanityImport{
+				{Import: "pack.ag", RepoURL: "https://github.com/vcabbage/root", Branch: "master"},
+				{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+				{Import: "pack.ag/amqp/internal/encoding", RepoURL: "https://github.com/vcabbage/amqp", Branch: "main", PathLen: 2},
+				{Import: "pack.ag/tools/cmd/lint", RepoURL: "https://github.com/vcabbage/tools", Branch: "master", PathLen: 2, Subdir: "go"},
+				{Import: "pack.ag/lab", RepoURL: "https://gitlab.com/vcabbage/lab", Branch: "main", GitLab: true},
+				{Import: "pack.ag/hg", RepoURL: "https://hg.example.com/hg", GoImportVCS: "hg"},
+				{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "dev", Variant: "dev"},
+			},
+		},
+		{
+			name:      "dir with open graph",
+			prefix:    "pack.ag",
+			layout:    layoutDir,
+			openGraph: true,
+			imports: []vanityImport{
+				{Import: "pack.ag", RepoURL: "https://github.com/vcabbage/root", Branch: "master", Synopsis: "Package root does things."},
+				{Import: "pack.ag/amqp/internal/encoding", RepoURL: "https://github.com/vcabbage/amqp", Branch: "main", PathLen: 2},
+				{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "dev", Variant: "dev"},
+			},
+		},
+		{
+			// The go-import prefix, rather than the prefix, gives
+			// the case of the import path.
+			name:   "case-insensitive",
+			prefix: "example.com/go",
+			layout: layoutFile,
+			imports: []vanityImport{
+				{Import: "example.com/Go/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+				{Import: "example.com/Go/amqp/cmd/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master", PathLen: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(tt.prefix)
+			cfg.pageTmpl = vanity.PageTemplate
+			cfg.layout = tt.layout
+			cfg.openGraph = tt.openGraph
+			cfg.rootBehavior = rootNone
+			cfg.caseInsensitive = true
+			cfg.out = t.TempDir()
+
+			w, err := generate(context.Background(), cfg, cfg.out, tt.imports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(w.hashes) != len(tt.imports) {
+				t.Fatalf("wrote %d pages, want %d", len(w.hashes), len(tt.imports))
+			}
+			written := make(map[string][]byte)
+			for rel := range w.hashes {
+				path := filepath.Join(cfg.out, filepath.FromSlash(rel))
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				written[path] = data
+			}
+
+			if err := normalize(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range written {
+				got, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("normalize changed %s:\n%s\nwant\n%s", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeRewrites(t *testing.T) {
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.layout = layoutFile
+	cfg.out = t.TempDir()
+
+	imprt := vanityImport{Import: "pack.ag/amqp/internal/encoding", RepoURL: "https://github.com/vcabbage/amqp", Branch: "main", PathLen: 2}
+	want, err := cfg.renderPage(context.Background(), imprt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rendering the same import again is byte for byte the same.
+	if again, err := cfg.renderPage(context.Background(), imprt); err != nil || !bytes.Equal(again, want) {
+		t.Fatalf("rendering again = %q, %v, want %q", again, err, want)
+	}
+
+	// A page with extra whitespace and reordered attributes, such as
+	// one written by hand or an older version.
+	path := filepath.Join(cfg.out, "amqp", "internal", "encoding.html")
+	writeTestFiles(t, cfg.out, map[string]string{
+		"amqp/internal/encoding.html": `<!DOCTYPE html>
+<head>
+    <meta content="pack.ag/amqp git https://github.com/vcabbage/amqp"   name="go-import">
+    <meta name="go-source" content="pack.ag/amqp https://github.com/vcabbage/amqp https://github.com/vcabbage/amqp/tree/main{/dir} https://github.com/vcabbage/amqp/blob/main{/dir}/{file}#L{line}">
+</head>
+</html>
+`,
+		"notes.html": "<html><body>Not generated.</body></html>\n",
+	})
+	if err := normalize(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("normalized page =\n%s\nwant\n%s", got, want)
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(cfg.out, "notes.html")); err != nil || string(got) != "<html><body>Not generated.</body></html>\n" {
+		t.Errorf("notes.html = %q, %v, want it untouched", got, err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findOrphans returns the pages in cfg.out, relative to it, which were
+// generated by govanity but wouldn't be generated for imports, such as
+// those of removed packages. Nothing in cfg.out is modified.
+func findOrphans(ctx context.Context, cfg *config, imports []vanityImport) ([]string, error) {
+	if _, err := os.Stat(cfg.out); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	tmpDir, err := ioutil.TempDir("", "govanity-orphans")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := generateSites(ctx, cfg, tmpDir, imports)
+	if err != nil {
+		return nil, err
+	}
+
+	return orphanPages(cfg.out, w.hashes)
+}
+
+// orphanPages returns the pages in dir, relative to it, which were
+// generated by govanity but aren't in hashes, the files written by a run.
+// Files which weren't generated, such as a CNAME or pages added by hand,
+// are never orphans, and dot-directories such as .git aren't searched.
+func orphanPages(dir string, hashes map[string]string) ([]string, error) {
+	var orphans []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".html" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := hashes[rel]; ok {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isGenerated(data) {
+			orphans = append(orphans, rel)
+		}
+		return nil
+	})
+	return orphans, err
+}
+
+// pruneBlocked returns why pages can't be pruned after a run in which
+// failed repositories failed to be scanned, or an empty string if they
+// can. The pages of repositories which failed, perhaps only because of
+// a transient error, would otherwise be deleted.
+func pruneBlocked(ctx context.Context, failed int) string {
+	switch {
+	case ctx.Err() != nil:
+		return "the run was cancelled"
+	case failed == 1:
+		return "1 repository failed"
+	case failed > 1:
+		return fmt.Sprintf("%d repositories failed", failed)
+	}
+	return ""
+}
+
+// prune deletes the orphaned pages in the output directory, recording
+// each deletion. Directories left empty are removed. Pages which were
+// kept rather than written aren't orphans.
+func (w *siteWriter) prune() error {
+	written := make(map[string]string, len(w.hashes)+len(w.kept))
+	for p, sum := range w.hashes {
+		written[p] = sum
+	}
+	for p := range w.kept {
+		written[p] = ""
+	}
+	orphans, err := orphanPages(w.dir, written)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		path := filepath.Join(w.dir, filepath.FromSlash(orphan))
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		w.record(path, changeDeleted)
+		removeEmptyDirs(w.dir, filepath.Dir(path))
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents within root while they're
+// empty.
+func removeEmptyDirs(root, dir string) {
+	for ; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		// Remove fails on directories which aren't empty.
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+const testPage = `<html><head><meta name="go-import" content="pack.ag/a git https://github.com/vcabbage/a"></head></html>`
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a.html":       testPage,
+		"stale.html":   testPage,
+		"kept.html":    testPage,
+		"old/b.html":   testPage,
+		"byhand.html":  "<html></html>",
+		"CNAME":        "pack.ag\n",
+		".git/x.html":  testPage,
+		".github/x.md": "",
+	})
+
+	w := &siteWriter{dir: dir}
+	w.hash(filepath.Join(dir, "a.html"), []byte(testPage))
+	w.keep(filepath.Join(dir, "kept.html"))
+	if err := w.prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{
+		"a.html":      true,
+		"stale.html":  false,
+		"kept.html":   true,
+		"old":         false,
+		"byhand.html": true,
+		"CNAME":       true,
+		".git/x.html": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %t, want %t", name, exists, want)
+		}
+	}
+
+	want := []fileChange{
+		{path: "old/b.html", kind: changeDeleted},
+		{path: "stale.html", kind: changeDeleted},
+	}
+	if !reflect.DeepEqual(w.changes, want) {
+		t.Errorf("changes = %+v, want %+v", w.changes, want)
+	}
+}
+
+func TestPruneBlocked(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		ctx    context.Context
+		failed int
+		want   string
+	}{
+		{ctx: context.Background(), failed: 0, want: ""},
+		{ctx: context.Background(), failed: 1, want: "1 repository failed"},
+		{ctx: context.Background(), failed: 3, want: "3 repositories failed"},
+		{ctx: cancelled, failed: 0, want: "the run was cancelled"},
+	}
+	for _, tt := range tests {
+		if got := pruneBlocked(tt.ctx, tt.failed); got != tt.want {
+			t.Errorf("pruneBlocked(%v, %d) = %q, want %q", tt.ctx.Err(), tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.layout = layoutFile
+	cfg.rootBehavior = rootNone
+	cfg.out = t.TempDir()
+	writeTestFiles(t, cfg.out, map[string]string{
+		"tftp.html":          testPage,
+		"stale.html":         testPage,
+		"old/internal.html":  testPage,
+		"byhand.html":        "<html><body>Not generated.</body></html>",
+		"CNAME":              "pack.ag\n",
+		".github/index.html": testPage,
+	})
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+	}
+
+	orphans, err := findOrphans(context.Background(), cfg, imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"old/internal.html", "stale.html"}; !reflect.DeepEqual(orphans, want) {
+		t.Errorf("orphans = %q, want %q", orphans, want)
+	}
+
+	// Finding orphans doesn't change the output directory.
+	for _, name := range []string{"stale.html", "old/internal.html"} {
+		if _, err := os.Stat(filepath.Join(cfg.out, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.out, "amqp.html")); !os.IsNotExist(err) {
+		t.Errorf("amqp.html was written: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// writePatch writes a unified diff of the changes generating imports would
+// make to cfg.out, without modifying it. Added, modified, and removed pages
+// are included; files in cfg.out which weren't generated are ignored.
+func writePatch(ctx context.Context, cfg *config, imports []vanityImport) error {
+	tmpDir, err := ioutil.TempDir("", "govanity-patch")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldDir := filepath.Join(tmpDir, "old")
+	newDir := filepath.Join(tmpDir, "new")
+	if err := os.Mkdir(oldDir, 0755); err != nil {
+		return err
+	}
+	if _, err := generateSites(ctx, cfg, newDir, imports); err != nil {
+		return err
+	}
+
+	// Copy the current version of every file which would be written.
+	err = filepath.Walk(newDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(newDir, path)
+		if err != nil {
+			return err
+		}
+		return copyIfExists(filepath.Join(cfg.out, rel), filepath.Join(oldDir, rel))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Copy previously generated pages which would no longer be written
+	// so they show up as removed.
+	if _, err := os.Stat(cfg.out); err == nil {
+		err = filepath.Walk(cfg.out, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) != ".html" {
+				return nil
+			}
+
+			rel, err := filepath.Rel(cfg.out, path)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(filepath.Join(newDir, rel)); err == nil {
+				return nil
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil || !isGenerated(data) {
+				return err
+			}
+			return writeFile(filepath.Join(oldDir, rel), data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cmd := commandContext(ctx, "git", "diff", "--no-index", "--src-prefix=a/", "--dst-prefix=b/", "old", "new")
+	cmd.Dir = tmpDir
+	diff, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// Exit status 1 indicates there are differences.
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Strip the temporary directory names from the file paths. Added and
+	// removed files use the same directory for both paths in git's header.
+	for _, dir := range []string{"old", "new"} {
+		diff = bytes.Replace(diff, []byte(" a/"+dir+"/"), []byte(" a/"), -1)
+		diff = bytes.Replace(diff, []byte(" b/"+dir+"/"), []byte(" b/"), -1)
+	}
+
+	if cfg.patch == "-" {
+		_, err = os.Stdout.Write(diff)
+		return err
+	}
+	return ioutil.WriteFile(cfg.patch, diff, 0644)
+}
+
+// copyIfExists copies the file at src to dst if src exists.
+func copyIfExists(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return writeFile(dst, data)
+}
+
+// writeFile writes data to path, creating parent directories as needed.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
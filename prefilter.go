@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// rawBaseURL is the base URL files of GitHub repositories are fetched from.
+var rawBaseURL = "https://raw.githubusercontent.com/"
+
+// prefilterRepos returns the repositories of repoURLs which may contain
+// vanity imports, checking each GitHub repository with up to cfg.prefilter
+// at a time. Repositories which can't contain a matching package, see
+// mayMatch, are left out so they aren't cloned. Repositories which
+// couldn't be checked are kept.
+func prefilterRepos(ctx context.Context, cfg *config, gh repoLister, repoURLs []string) []string {
+	var (
+		keep = make([]bool, len(repoURLs))
+		sem  = make(chan struct{}, cfg.prefilter)
+		wg   sync.WaitGroup
+	)
+	for i, repoURL := range repoURLs {
+		owner, repo, ok := cfg.githubRepo(repoURL)
+		if !ok || cfg.orgs[owner].Names {
+			keep[i] = true
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, repoURL, owner, repo string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			ok, err := mayMatch(ctx, cfg, gh, owner, repo)
+			switch {
+			case err != nil:
+				warnf(repoURL, "prefiltering: %v", err)
+				keep[i] = true
+			case ok:
+				keep[i] = true
+			default:
+				debugf("%s: no module matches prefix, skipping\n", repoURL)
+			}
+		}(i, repoURL, owner, repo)
+	}
+	wg.Wait()
+
+	var filtered []string
+	for i, repoURL := range repoURLs {
+		if keep[i] {
+			filtered = append(filtered, repoURL)
+		}
+	}
+	return filtered
+}
+
+// mayMatch reports whether the GitHub repository owner/repo may contain
+// packages matching the prefix. Its root go.mod is fetched first, and if
+// its module path doesn't match the import comments of its packages may
+// still match, unless they're ignored by -module-only. Then the repository
+// only matches if it has a nested module which does, or a vanity.OverridesFile.
+func mayMatch(ctx context.Context, cfg *config, gh repoLister, owner, repo string) (bool, error) {
+	ref := "HEAD"
+	if commit := cfg.repos[cfg.githubURL+"/"+owner+"/"+repo].Commit; commit != "" {
+		ref = commit
+	}
+
+	module, err := fetchModulePath(ctx, cfg, owner, repo, ref, "go.mod")
+	if err != nil {
+		return true, fmt.Errorf("fetching go.mod: %v", err)
+	}
+	if module == "" || cfg.inModulePrefix(module) || !cfg.moduleOnly {
+		return true, nil
+	}
+
+	var files []string
+	err = cfg.retry.do(ctx, func() (err error) {
+		files, err = gh.listFiles(ctx, owner, repo, ref)
+		return err
+	})
+	if err != nil {
+		return true, fmt.Errorf("listing files: %v", err)
+	}
+	for _, file := range files {
+		if file == vanity.OverridesFile {
+			return true, nil
+		}
+		if file == "go.mod" || path.Base(file) != "go.mod" || ignoredDir(path.Dir(file)) {
+			continue
+		}
+		module, err := fetchModulePath(ctx, cfg, owner, repo, ref, file)
+		if err != nil {
+			return true, fmt.Errorf("fetching %s: %v", file, err)
+		}
+		if cfg.inModulePrefix(module) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fetchModulePath returns the module path declared by the go.mod file at
+// ref of owner/repo, or an empty string if there isn't one.
+func fetchModulePath(ctx context.Context, cfg *config, owner, repo, ref, file string) (string, error) {
+	base := rawBaseURL
+	if cfg.githubURL != defaultGitHubURL {
+		// GitHub Enterprise serves raw files from the instance.
+		base = cfg.githubURL + "/raw/"
+	}
+	req, err := http.NewRequest("GET", base+owner+"/"+repo+"/"+ref+"/"+file, nil)
+	if err != nil {
+		return "", err
+	}
+	if cfg.githubToken != "" {
+		req.Header.Set("Authorization", "token "+cfg.githubToken)
+	}
+
+	var data []byte
+	err = cfg.retry.do(ctx, func() error {
+		resp, err := cfg.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			data, err = ioutil.ReadAll(resp.Body)
+			return err
+		case http.StatusNotFound:
+			data = nil
+			return nil
+		}
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+		if transientStatus(resp.StatusCode) {
+			return &transientError{err: err}
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return modulePath(data), nil
+}
+
+// modulePath returns the module path declared in the go.mod data.
+func modulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(fields[1]); err == nil {
+			return path
+		}
+		return fields[1]
+	}
+	return ""
+}
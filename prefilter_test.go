@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestPrefilterRepos(t *testing.T) {
+	// Raw files by owner/repo/ref/path.
+	raw := map[string]string{
+		"vcabbage/a/HEAD/go.mod":    "module pack.ag/a\n",
+		"vcabbage/b/HEAD/go.mod":    "module github.com/vcabbage/b\n",
+		"vcabbage/b/HEAD/v2/go.mod": "module pack.ag/b/v2\n",
+		"vcabbage/c/HEAD/go.mod":    "module github.com/vcabbage/c\n",
+		"vcabbage/c/HEAD/x/go.mod":  "module github.com/vcabbage/c/x\n",
+		"vcabbage/e/HEAD/go.mod":    "module github.com/vcabbage/e\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := raw[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+	defer func(base string) { rawBaseURL = base }(rawBaseURL)
+	rawBaseURL = srv.URL + "/"
+
+	gh := &fakeLister{files: map[string][]string{
+		"vcabbage/b": {"go.mod", "b.go", "v2/go.mod", "v2/b.go"},
+		"vcabbage/c": {"go.mod", "c.go", "x/go.mod", "testdata/go.mod"},
+		"vcabbage/e": {"go.mod", vanity.OverridesFile},
+	}}
+	repoURLs := []string{
+		"https://github.com/vcabbage/a", // root module matches
+		"https://github.com/vcabbage/b", // nested module matches
+		"https://github.com/vcabbage/c", // no module matches
+		"https://github.com/vcabbage/d", // no go.mod
+		"https://github.com/vcabbage/e", // .govanity.json
+		"https://gitlab.com/vcabbage/f", // not on GitHub
+	}
+
+	tests := []struct {
+		name       string
+		moduleOnly bool
+		want       []string
+	}{
+		{
+			name: "import comments",
+			want: repoURLs,
+		},
+		{
+			name:       "module only",
+			moduleOnly: true,
+			want: []string{
+				"https://github.com/vcabbage/a",
+				"https://github.com/vcabbage/b",
+				"https://github.com/vcabbage/d",
+				"https://github.com/vcabbage/e",
+				"https://gitlab.com/vcabbage/f",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("pack.ag")
+			cfg.githubURL = defaultGitHubURL
+			cfg.prefilter = 2
+			cfg.moduleOnly = tt.moduleOnly
+			got := prefilterRepos(context.Background(), cfg, gh, repoURLs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prefilterRepos() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{data: "module pack.ag/a\n", want: "pack.ag/a"},
+		{data: "// comment\nmodule \"pack.ag/a\" // trailing\n\ngo 1.16\n", want: "pack.ag/a"},
+		{data: "go 1.16\n", want: ""},
+		{data: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := modulePath([]byte(tt.data)); got != tt.want {
+			t.Errorf("modulePath(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// parsePrefixes returns the comma separated vanity URL prefixes of s.
+func parsePrefixes(s string) ([]string, error) {
+	var prefixes []string
+	for _, prefix := range strings.Split(s, ",") {
+		prefix = vanity.CleanImportPath(strings.TrimSpace(prefix))
+		if prefix == "" {
+			continue
+		}
+		if _, err := url.Parse("//" + prefix); err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// matchPrefix returns the longest of cfg.prefixes which importPath
+// begins with, or an empty string if there isn't one.
+func (cfg *config) matchPrefix(importPath string) string {
+	return vanity.MatchPrefix(cfg.prefixes, importPath, cfg.caseInsensitive)
+}
+
+// inModulePrefix reports whether the module path is one of cfg.prefixes
+// or within one of them.
+func (cfg *config) inModulePrefix(module string) bool {
+	for _, prefix := range cfg.prefixes {
+		if len(module) == len(prefix) && vanity.HasImportPrefix(module, prefix, cfg.caseInsensitive) ||
+			vanity.HasImportPrefix(module, prefix+"/", cfg.caseInsensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimImportPrefix returns importPath without prefix, which may differ in
+// case when -case-insensitive matched it.
+func trimImportPrefix(importPath, prefix string) string {
+	if vanity.HasImportPrefix(importPath, prefix, true) {
+		return importPath[len(prefix):]
+	}
+	return importPath
+}
+
+// forPrefix returns a copy of cfg for the site of prefix, one of
+// cfg.prefixes, which is written to the prefix's directory of cfg.out.
+func (cfg *config) forPrefix(prefix string) *config {
+	c := *cfg
+	c.prefix = prefix
+	c.prefixes = []string{prefix}
+	c.prefixURL, _ = url.Parse("//" + prefix)
+	c.out = filepath.Join(cfg.out, filepath.FromSlash(prefix))
+	return &c
+}
+
+// pagePath returns the path of the page of imprt in dir. With more than
+// one prefix it's in the directory of the prefix matching its import path.
+func (cfg *config) pagePath(imprt vanityImport, dir string) string {
+	if len(cfg.prefixes) < 2 {
+		return htmlPath(imprt, cfg.prefix, dir, cfg.layout)
+	}
+	prefix := cfg.matchPrefix(imprt.Import)
+	return htmlPath(imprt, prefix, filepath.Join(dir, filepath.FromSlash(prefix)), cfg.layout)
+}
+
+// generateSites is generate for every prefix. With more than one prefix,
+// each site is written to the prefix's directory of dir, such as
+// dir/pack.ag and dir/example.com/go, and the changes recorded by the
+// returned writer are relative to dir.
+func generateSites(ctx context.Context, cfg *config, dir string, imports []vanityImport) (*siteWriter, error) {
+	if len(cfg.prefixes) < 2 {
+		return generate(ctx, cfg, dir, imports)
+	}
+
+	all := &siteWriter{dir: dir, hashes: make(map[string]string)}
+	for _, prefix := range cfg.prefixes {
+		var matched []vanityImport
+		for _, imprt := range imports {
+			if cfg.matchPrefix(imprt.Import) == prefix {
+				matched = append(matched, imprt)
+			}
+		}
+
+		pcfg := cfg.forPrefix(prefix)
+		w, err := generate(ctx, pcfg, filepath.Join(dir, filepath.FromSlash(prefix)), matched)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", prefix, err)
+		}
+		for _, c := range w.changes {
+			all.changes = append(all.changes, fileChange{path: path.Join(prefix, c.path), kind: c.kind})
+		}
+		for p, sum := range w.hashes {
+			all.hashes[path.Join(prefix, p)] = sum
+		}
+		for p := range w.kept {
+			all.keep(filepath.Join(dir, filepath.FromSlash(path.Join(prefix, p))))
+		}
+	}
+	return all, nil
+}
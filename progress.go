@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// progressWidth is the number of characters in the bar.
+const progressWidth = 30
+
+// progressBar draws the progress of a phase of the run below the logged
+// messages. Only a single bar is active at a time, logf redraws it after
+// each message.
+type progressBar struct {
+	label   string
+	total   int
+	done    int
+	current string
+}
+
+// bar is the active progress bar, guarded by logMu.
+var bar *progressBar
+
+// progressOut is where progress bars are drawn.
+var progressOut = os.Stdout
+
+// startProgress starts a progress bar for a phase of total steps when
+// -progress is set without -quiet and stdout is a terminal, otherwise
+// nil is returned and messages are logged as usual. Methods of a nil *progressBar do
+// nothing.
+func startProgress(cfg *config, label string, total int) *progressBar {
+	if !cfg.progress || cfg.quiet || !isTerminal(progressOut) {
+		return nil
+	}
+
+	b := &progressBar{label: label, total: total}
+	logMu.Lock()
+	bar = b
+	b.draw()
+	logMu.Unlock()
+	return b
+}
+
+// step marks the start of the step named current.
+func (b *progressBar) step(current string) {
+	if b == nil {
+		return
+	}
+	logMu.Lock()
+	b.current = current
+	b.draw()
+	logMu.Unlock()
+}
+
+// stepDone marks the current step complete.
+func (b *progressBar) stepDone() {
+	if b == nil {
+		return
+	}
+	logMu.Lock()
+	b.done++
+	b.draw()
+	logMu.Unlock()
+}
+
+// finish removes the bar, leaving a summary line.
+func (b *progressBar) finish() {
+	if b == nil {
+		return
+	}
+	logMu.Lock()
+	b.current = ""
+	b.draw()
+	fmt.Fprint(progressOut, "\n")
+	bar = nil
+	logMu.Unlock()
+}
+
+// draw replaces the current line with the bar, logMu must be held.
+func (b *progressBar) draw() {
+	filled := progressWidth
+	if b.total > 0 {
+		filled = b.done * progressWidth / b.total
+	}
+	fmt.Fprintf(progressOut, "\r\033[K%s [%s%s] %d/%d %s",
+		b.label,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressWidth-filled),
+		b.done, b.total,
+		redact(b.current),
+	)
+}
+
+// active reports whether b is drawn, in which case routine messages
+// should be left out.
+func (b *progressBar) active() bool {
+	return b != nil
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
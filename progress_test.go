@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProgress(t *testing.T) {
+	// /dev/null is a character device, so it's drawn to like a
+	// terminal.
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	defer func(f *os.File) { progressOut = f }(progressOut)
+
+	tests := []struct {
+		name     string
+		progress bool
+		quiet    bool
+		out      *os.File
+		want     bool
+	}{
+		{name: "terminal", progress: true, out: devNull, want: true},
+		{name: "not set", out: devNull},
+		{name: "quiet", progress: true, quiet: true, out: devNull},
+		{name: "not a terminal", progress: true, out: file},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progressOut = tt.out
+			cfg := testConfig("pack.ag")
+			cfg.progress = tt.progress
+			cfg.quiet = tt.quiet
+
+			b := startProgress(cfg, "Scanning", 1)
+			b.finish()
+			if got := b.active(); got != tt.want {
+				t.Errorf("startProgress().active() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// httpClient returns the client used for GitHub API and other HTTP
+// requests, which uses cfg.proxy if set.
+func (cfg *config) httpClient() *http.Client {
+	if cfg.proxyURL == nil {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(cfg.proxyURL)
+	return &http.Client{Transport: transport}
+}
+
+// gitEnv returns the environment of git commands which access remote
+// repositories, configuring http.proxy if cfg.proxy is set and the
+// GitHub authorization header if a token is set. A nil environment is
+// returned otherwise so the current one is used.
+func (cfg *config) gitEnv() []string {
+	var settings [][2]string
+	if cfg.proxyURL != nil {
+		settings = append(settings, [2]string{"http.proxy", cfg.proxyURL.String()})
+	}
+	if cfg.githubToken != "" {
+		// Passing the token in the environment rather than the URL
+		// keeps it out of logged URLs and the clone's configuration.
+		settings = append(settings, [2]string{"http." + cfg.githubURL + "/.extraHeader", "Authorization: Basic " + cfg.githubCredentials()})
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	// Settings already passed to govanity in the environment are kept,
+	// numbering these after them.
+	count, err := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	if err != nil || count < 0 {
+		count = 0
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "GIT_CONFIG_COUNT=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+len(settings)))
+	for i, setting := range settings {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count+i, setting[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count+i, setting[1]),
+		)
+	}
+	return env
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGitEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string // set before gitEnv
+		want map[string]string // git config values
+	}{
+		{
+			name: "no existing settings",
+			env:  map[string]string{"GIT_CONFIG_COUNT": ""},
+			want: map[string]string{"http.proxy": "http://proxy.example:3128"},
+		},
+		{
+			name: "existing settings",
+			env: map[string]string{
+				"GIT_CONFIG_COUNT":   "2",
+				"GIT_CONFIG_KEY_0":   "user.name",
+				"GIT_CONFIG_VALUE_0": "vcabbage",
+				"GIT_CONFIG_KEY_1":   "core.askPass",
+				"GIT_CONFIG_VALUE_1": "true",
+			},
+			want: map[string]string{
+				"user.name":    "vcabbage",
+				"core.askpass": "true",
+				"http.proxy":   "http://proxy.example:3128",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := testConfig("pack.ag")
+			cfg.githubURL = defaultGitHubURL
+			cfg.githubToken = "token"
+			cfg.proxyURL, _ = url.Parse("http://proxy.example:3128")
+			env := cfg.gitEnv()
+
+			tt.want["http."+defaultGitHubURL+"/.extraheader"] = "Authorization: Basic " + cfg.githubCredentials()
+			for key, want := range tt.want {
+				cmd := exec.Command("git", "config", "--get", key)
+				cmd.Env = env
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("git config --get %s: %v", key, err)
+				}
+				if got := strings.TrimSpace(string(out)); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+)
+
+// secrets holds values, such as API tokens, which must never be printed.
+var secrets []string
+
+// addSecret registers s to be redacted from all output.
+func addSecret(s string) {
+	if s != "" {
+		secrets = append(secrets, s)
+	}
+}
+
+// redact replaces each registered secret in s with ***.
+func redact(s string) string {
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, "***", -1)
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// Values of -redirect, other values are URL templates.
+const (
+	redirectRepo    = "repo"
+	redirectPkgsite = "pkgsite"
+)
+
+// parseRedirect returns the template of the URL visitors are sent to for
+// a -redirect value, or nil when they're sent to the repository.
+func parseRedirect(redirect string) (*template.Template, error) {
+	switch redirect {
+	case "", redirectRepo:
+		return nil, nil
+	case redirectPkgsite:
+		redirect = "https://pkg.go.dev/{{.Import}}"
+	}
+	return template.New("redirect").Parse(redirect)
+}
+
+// redirectURL returns the URL visitors to the page of imprt are sent to,
+// or "" when they're sent to the repository.
+func (cfg *config) redirectURL(imprt vanityImport) (string, error) {
+	if cfg.redirectTmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := cfg.redirectTmpl.Execute(&buf, imprt); err != nil {
+		return "", fmt.Errorf("%s: redirect: %v", imprt.Import, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// resolverPrefix marks entries of the search list which are passed to
+// the -resolver-command.
+const resolverPrefix = "resolver:"
+
+// resolvedRepo is a repository printed by the -resolver-command.
+type resolvedRepo struct {
+	URL    string `json:"url"`     // cloned and used in go-import
+	WebURL string `json:"web_url"` // source links, defaults to URL
+}
+
+// resolveRepos runs the -resolver-command with entry as its last argument,
+// returning the URLs of the repositories it prints as a JSON array of
+// resolvedRepo. The web URL of each repository is recorded in cfg.repos
+// unless the configuration file already sets a source URL.
+func resolveRepos(ctx context.Context, cfg *config, entry string) ([]string, error) {
+	args := strings.Fields(cfg.resolverCommand)
+	cmd := commandContext(ctx, args[0], append(args[1:], entry)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running resolver: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var repos []resolvedRepo
+	if err := json.Unmarshal(out, &repos); err != nil {
+		return nil, fmt.Errorf("parsing resolver output: %v", err)
+	}
+
+	if cfg.repos == nil {
+		cfg.repos = make(map[string]repoConfig)
+	}
+	var repoURLs []string
+	for _, repo := range repos {
+		url := strings.TrimSuffix(repo.URL, "/")
+		if url == "" {
+			return nil, fmt.Errorf("resolver printed a repository without a url")
+		}
+		if rc := cfg.repos[url]; repo.WebURL != "" && rc.SourceURL == "" {
+			rc.SourceURL = repo.WebURL
+			cfg.repos[url] = rc
+		}
+		repoURLs = append(repoURLs, url)
+	}
+	return repoURLs, nil
+}
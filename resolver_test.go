@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// resolverScript is a fake -resolver-command printing canned JSON for the
+// entry given as its last argument, after checking it's passed the
+// arguments of the command.
+const resolverScript = `#!/bin/sh
+if [ "$1" != "--host=git.example.com" ]; then
+	echo "missing arguments" >&2
+	exit 2
+fi
+case "$2" in
+team)
+	cat <<'JSON'
+[
+  {"url": "https://git.example.com/team/tftp.git", "web_url": "https://code.example.com/team/tftp"},
+  {"url": "https://git.example.com/team/amqp/"}
+]
+JSON
+	;;
+empty)
+	echo '[]'
+	;;
+garbage)
+	echo 'not json'
+	;;
+nourl)
+	echo '[{"web_url": "https://code.example.com/team/tftp"}]'
+	;;
+*)
+	echo "unknown entry $2" >&2
+	exit 1
+	;;
+esac
+`
+
+func TestResolveRepos(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"resolver.sh": resolverScript})
+	script := filepath.Join(dir, "resolver.sh")
+
+	tests := []struct {
+		entry   string
+		want    []string
+		sources map[string]string
+		err     string
+	}{
+		{
+			entry: "team",
+			want:  []string{"https://git.example.com/team/tftp.git", "https://git.example.com/team/amqp"},
+			sources: map[string]string{
+				"https://git.example.com/team/tftp.git": "https://code.example.com/team/tftp",
+				"https://git.example.com/team/amqp":     "",
+			},
+		},
+		{entry: "empty"},
+		{entry: "garbage", err: "parsing resolver output"},
+		{entry: "nourl", err: "resolver printed a repository without a url"},
+		{entry: "missing", err: "unknown entry missing"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("pack.ag")
+		cfg.resolverCommand = "sh " + script + " --host=git.example.com"
+		got, err := resolveRepos(context.Background(), cfg, tt.entry)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("resolveRepos(%s) = %v, want %s", tt.entry, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveRepos(%s) = %q, want %q", tt.entry, got, tt.want)
+		}
+		for url, source := range tt.sources {
+			if got := cfg.repos[url].SourceURL; got != source {
+				t.Errorf("%s: source URL = %q, want %q", url, got, source)
+			}
+		}
+	}
+}
+
+func TestGetPotentialReposResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"resolver.sh": resolverScript})
+
+	cfg := testConfig("pack.ag")
+	cfg.resolverCommand = "sh " + filepath.Join(dir, "resolver.sh") + " --host=git.example.com"
+	// The same entry twice only lists each repository once, and the
+	// configured source URL is kept.
+	cfg.searchList = []string{resolverPrefix + "team", resolverPrefix + "team"}
+	cfg.repos = map[string]repoConfig{
+		"https://git.example.com/team/tftp.git": {SourceURL: "https://browse.example.com/tftp"},
+	}
+	got, err := getPotentialRepos(context.Background(), &fakeLister{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://git.example.com/team/tftp.git", "https://git.example.com/team/amqp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("repos = %q, want %q", got, want)
+	}
+	if got := cfg.repos["https://git.example.com/team/tftp.git"].SourceURL; got != "https://browse.example.com/tftp" {
+		t.Errorf("source URL = %q, want the configured one", got)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// retryPolicy controls how failed git, go list, and GitHub API
+// operations are retried.
+type retryPolicy struct {
+	attempts  int           // total attempts, including the first
+	baseDelay time.Duration // delay before the first retry, doubled for each subsequent retry
+	maxDelay  time.Duration // upper bound of the delay
+	jitter    float64       // fraction of the delay randomly added or subtracted
+
+	// rateLimitWait is the longest to wait for a GitHub rate limit to
+	// reset before retrying, rather than failing with rateLimitedError.
+	// Waits don't count towards attempts.
+	rateLimitWait time.Duration
+}
+
+// rateLimitedError is returned when the GitHub API rate limit is exceeded
+// and doesn't reset within the retry policy's rateLimitWait.
+type rateLimitedError struct {
+	reset time.Time
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exceeded until %s, set -token to raise the limit or -rate-limit-wait to wait for it to reset", e.reset.Format(time.RFC3339))
+}
+
+// isRateLimited reports whether err is a rateLimitedError.
+func isRateLimited(err error) bool {
+	var rlErr *rateLimitedError
+	return errors.As(err, &rlErr)
+}
+
+// rateLimitReset returns when the GitHub rate limit which caused err
+// resets, if it was caused by one.
+func rateLimitReset(err error) (time.Time, bool) {
+	var (
+		rlErr    *github.RateLimitError
+		abuseErr *github.AbuseRateLimitError
+	)
+	switch {
+	case errors.As(err, &rlErr):
+		return rlErr.Rate.Reset.Time, true
+	case errors.As(err, &abuseErr):
+		if abuseErr.RetryAfter != nil {
+			return time.Now().Add(*abuseErr.RetryAfter), true
+		}
+		return time.Now().Add(time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// delay returns the delay before retry n, starting at 1, without jitter.
+func (p retryPolicy) delay(n int) time.Duration {
+	d := p.baseDelay
+	for i := 1; i < n && d < p.maxDelay; i++ {
+		d *= 2
+	}
+	if d > p.maxDelay {
+		d = p.maxDelay
+	}
+	return d
+}
+
+// do calls fn until it succeeds, returns an error that can't be fixed by
+// retrying, or the attempts are exhausted, returning the last error.
+// Calls which hit a GitHub rate limit are retried once it resets, without
+// counting as an attempt, if that's within rateLimitWait.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	var err error
+	for n := 0; n < p.attempts; {
+		var d time.Duration
+		if reset, ok := rateLimitReset(err); ok {
+			d = time.Until(reset) + time.Second
+			if d > p.rateLimitWait {
+				return &rateLimitedError{reset: reset}
+			}
+			warnf("", "GitHub API rate limit exceeded, waiting %v for it to reset", d.Round(time.Second))
+		} else if n > 0 {
+			d = p.delay(n)
+			if p.jitter > 0 {
+				d += time.Duration((rand.Float64()*2 - 1) * p.jitter * float64(d))
+			}
+		}
+		if d > 0 {
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return err
+			}
+		}
+
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+		if _, ok := rateLimitReset(err); !ok {
+			n++
+		}
+	}
+	return err
+}
+
+// retryable reports whether retrying the operation which returned err
+// may succeed. Only network errors, timeouts, rate limiting, and server
+// errors are transient, others such as a missing repository or a go.mod
+// which can't be parsed would fail again.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if _, ok := rateLimitReset(err); ok {
+		return true
+	}
+
+	var (
+		errResp   *github.ErrorResponse
+		glErr     *gitlabError
+		transient *transientError
+		exitErr   *exec.ExitError
+		netErr    net.Error
+	)
+	switch {
+	case errors.As(err, &errResp) && errResp.Response != nil:
+		return transientStatus(errResp.Response.StatusCode)
+	case errors.As(err, &glErr):
+		return transientStatus(glErr.status)
+	case errors.As(err, &transient):
+		return true
+	case errors.As(err, &exitErr):
+		// Commands run with Output capture their stderr.
+		return transientOutput(exitErr.Stderr)
+	case errors.As(err, &netErr):
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// transientStatus reports whether an HTTP response with status may
+// succeed if the request is retried.
+func transientStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// transientError is an error which retrying may fix, such as a git
+// command which failed to reach its remote.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transientGitOutputs are the lower cased messages which git prints when
+// it fails because of the network or the server.
+var transientGitOutputs = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"connection timed out",
+	"operation timed out",
+	"connection refused",
+	"connection reset",
+	"network is unreachable",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"the requested url returned error: 5",
+	"the requested url returned error: 429",
+}
+
+// transientOutput reports whether the output of a failed git command
+// reports a failure which retrying may fix.
+func transientOutput(output []byte) bool {
+	output = bytes.ToLower(output)
+	for _, msg := range transientGitOutputs {
+		if bytes.Contains(output, []byte(msg)) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitError returns err, the error of a git command which printed output,
+// marked as transient if the output reports a failure which retrying may
+// fix.
+func gitError(err error, output []byte) error {
+	if transientOutput(output) {
+		return &transientError{err: err}
+	}
+	return err
+}
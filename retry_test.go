@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestRetryDelay(t *testing.T) {
+	p := retryPolicy{baseDelay: time.Second, maxDelay: 10 * time.Second}
+	want := []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	for i, w := range want {
+		if got := p.delay(i + 1); got != w {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	transient := &transientError{err: errors.New("connection reset")}
+	permanent := errors.New("parsing go.mod")
+
+	tests := []struct {
+		name      string
+		attempts  int
+		errs      []error // returned by each call, nil once exhausted
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", attempts: 3, wantCalls: 1},
+		{name: "transient then success", attempts: 3, errs: []error{transient}, wantCalls: 2},
+		{name: "exhausted", attempts: 3, errs: []error{transient, transient, transient, transient}, wantCalls: 3, wantErr: transient},
+		{name: "permanent", attempts: 3, errs: []error{permanent}, wantCalls: 1, wantErr: permanent},
+		{name: "single attempt", attempts: 1, errs: []error{transient}, wantCalls: 1, wantErr: transient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retryPolicy{attempts: tt.attempts, baseDelay: time.Millisecond, maxDelay: time.Millisecond}
+			calls := 0
+			err := p.do(context.Background(), func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("do() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	response := func(status int) *github.ErrorResponse {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: status}}
+	}
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: errors.New("go list: exit status 1"), want: false},
+		{err: context.Canceled, want: false},
+		{err: fmt.Errorf("clone: %w", context.DeadlineExceeded), want: false},
+		{err: response(http.StatusNotFound), want: false},
+		{err: response(http.StatusBadGateway), want: true},
+		{err: response(http.StatusTooManyRequests), want: true},
+		{err: &github.RateLimitError{Response: &http.Response{}}, want: true},
+		{err: &gitlabError{status: http.StatusUnauthorized}, want: false},
+		{err: &gitlabError{status: http.StatusServiceUnavailable}, want: true},
+		{err: &url.Error{Op: "Get", URL: "https://github.com", Err: &net.DNSError{Err: "no such host"}}, want: true},
+		{err: &transientError{err: errors.New("git clone: early EOF")}, want: true},
+		{err: &exec.ExitError{Stderr: []byte("fatal: unable to access '...': Could not resolve host: github.com")}, want: true},
+		{err: &exec.ExitError{Stderr: []byte("fatal: repository 'https://github.com/vcabbage/nope/' not found")}, want: false},
+		{err: io.ErrUnexpectedEOF, want: true},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGitError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{output: "fatal: unable to access 'https://github.com/a/b/': The requested URL returned error: 502", want: true},
+		{output: "fatal: the remote end hung up unexpectedly", want: true},
+		{output: "fatal: Remote branch v2 not found in upstream origin", want: false},
+	}
+	for _, tt := range tests {
+		err := gitError(errors.New("git clone: exit status 128"), []byte(tt.output))
+		if got := retryable(err); got != tt.want {
+			t.Errorf("retryable(gitError(%q)) = %t, want %t", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// readSearchFile reads the search entries in the file at path, one per
+// line. Blank lines and lines beginning with # are ignored.
+//
+// Example:
+//
+//	# orgs and users
+//	vcabbage
+//	# individual repositories
+//	packag/tftp
+func readSearchFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selfTest renders the page of each import and checks it with
+// validatePage, reporting every page which fails.
+func selfTest(ctx context.Context, cfg *config, imports []vanityImport) error {
+	failed := 0
+	for _, imprt := range imports {
+		html, err := cfg.renderPage(ctx, imprt)
+		if err == nil {
+			err = validatePage(imprt, html)
+		}
+		if err != nil {
+			warnf("", "self test failed for %s: %v", imprt.Import, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pages failed the self test", failed, len(imports))
+	}
+	logf("Self test passed for %d pages.\n", len(imports))
+	return nil
+}
+
+// voidElements are the HTML elements which don't have an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// optionalTags are the HTML elements whose start and end tags may be
+// omitted, so they aren't required to be balanced.
+var optionalTags = map[string]bool{"html": true, "head": true, "body": true}
+
+// validatePage checks that data, the page of imprt, is well-formed HTML
+// with go-import and go-source meta tags for the import.
+func validatePage(imprt vanityImport, data []byte) error {
+	var open []string
+	z := html.NewTokenizer(bytes.NewReader(data))
+tokens:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			break tokens
+		case html.StartTagToken:
+			if name, _ := z.TagName(); !voidElements[string(name)] && !optionalTags[string(name)] {
+				open = append(open, string(name))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if optionalTags[string(name)] {
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != string(name) {
+				return fmt.Errorf("unexpected </%s>", name)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed <%s>", open[len(open)-1])
+	}
+
+	meta, err := parseMeta(data)
+	if err != nil {
+		return err
+	}
+
+	goImport := strings.Split(meta["go-import"], " ")
+	fields := 3
+	if imprt.Subdir != "" {
+		fields = 4
+	}
+	if len(goImport) != fields {
+		return fmt.Errorf("go-import %q has %d fields, expected %d", meta["go-import"], len(goImport), fields)
+	}
+	prefix := goImport[0]
+	if imprt.Import != prefix && !strings.HasPrefix(imprt.Import, prefix+"/") {
+		return fmt.Errorf("go-import prefix %s does not match %s", prefix, imprt.Import)
+	}
+	if goImport[1] != imprt.VCS() || goImport[2] == "" {
+		return fmt.Errorf("malformed go-import %q", meta["go-import"])
+	}
+
+	// go-source is only written for git repositories.
+	if imprt.VCS() != "git" {
+		return nil
+	}
+	if goSource := strings.Split(meta["go-source"], " "); len(goSource) != 4 || goSource[0] != prefix {
+		return fmt.Errorf("malformed go-source %q", meta["go-source"])
+	}
+	return nil
+}
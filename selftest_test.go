@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestValidatePage(t *testing.T) {
+	imprt := vanityImport{Import: "pack.ag/tftp/netascii", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master", PathLen: 1}
+	goSource := `<meta name="go-source" content="pack.ag/tftp https://github.com/vcabbage/tftp https://github.com/vcabbage/tftp/tree/master{/dir} https://github.com/vcabbage/tftp/blob/master{/dir}/{file}#L{line}">`
+	tests := []struct {
+		name string
+		page string
+		err  string
+	}{
+		{
+			name: "valid",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp">` + goSource + `</head></html>`,
+		},
+		{
+			name: "mismatched end tag",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp">` + goSource + `</head><body><div>moved</span></body></html>`,
+			err:  "unexpected </span>",
+		},
+		{
+			name: "unclosed head",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp">` + goSource + `<div>`,
+			err:  "unclosed <div>",
+		},
+		{
+			name: "two fields",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp https://github.com/vcabbage/tftp">` + goSource + `</head></html>`,
+			err:  "has 2 fields, expected 3",
+		},
+		{
+			name: "four fields",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp extra">` + goSource + `</head></html>`,
+			err:  "has 4 fields, expected 3",
+		},
+		{
+			// A space in the import path splits it into another field.
+			name: "space in import path",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/t ftp git https://github.com/vcabbage/tftp">` + goSource + `</head></html>`,
+			err:  "has 4 fields, expected 3",
+		},
+		{
+			name: "other prefix",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/amqp git https://github.com/vcabbage/tftp">` + goSource + `</head></html>`,
+			err:  "go-import prefix pack.ag/amqp does not match pack.ag/tftp/netascii",
+		},
+		{
+			name: "no go-source",
+			page: `<!DOCTYPE html><head><meta name="go-import" content="pack.ag/tftp git https://github.com/vcabbage/tftp"></head></html>`,
+			err:  "malformed go-source",
+		},
+	}
+	for _, tt := range tests {
+		err := validatePage(imprt, []byte(tt.page))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: validatePage() = %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: validatePage() = %v, want %s", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		{Import: "pack.ag/amqp/internal", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master", PathLen: 1, MovedTo: "pack.ag/amqp/v2/internal"},
+		{Import: "pack.ag/tools/cmd/lint", RepoURL: "https://github.com/vcabbage/tools", Branch: "master", PathLen: 2, Subdir: "go"},
+		{Import: "pack.ag/hg", RepoURL: "https://hg.example.com/hg", GoImportVCS: "hg"},
+	}
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.openGraph = true
+	if err := selfTest(context.Background(), cfg, imports); err != nil {
+		t.Fatal(err)
+	}
+
+	// A template which leaves out the VCS fails for every page.
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"page.html": `<!DOCTYPE html><head><meta name="go-import" content="{{.ImportPrefix}} {{.ImportURL}}"></head></html>`,
+	})
+	var err error
+	if cfg.pageTmpl, err = vanity.ParsePageTemplate(filepath.Join(dir, "page.html")); err != nil {
+		t.Fatal(err)
+	}
+	err = selfTest(context.Background(), cfg, imports)
+	if err == nil || err.Error() != "4 of 4 pages failed the self test" {
+		t.Errorf("selfTest() = %v, want every page to fail", err)
+	}
+}
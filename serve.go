@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serveImports serves the pages of imports over HTTP at addr until ctx is
+// done, rendering them for each request rather than writing files. A
+// request for a package below an import, such as one without its own
+// page, is served the page of the longest matching import.
+func serveImports(ctx context.Context, cfg *config, addr string, imports []vanityImport) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: importHandler(cfg, imports)}
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(shutdownCtx)
+	}()
+
+	logf("Serving %d imports at http://%s\n", len(imports), ln.Addr())
+
+	err = srv.Serve(ln)
+	if err == http.ErrServerClosed {
+		return <-shutdown
+	}
+	return err
+}
+
+// importHandler renders the page of the import matching each request,
+// and serves the icons the pages link to. The import at the prefix
+// itself, if there is one, is served at the root.
+func importHandler(cfg *config, imports []vanityImport) http.Handler {
+	base := strings.TrimSuffix("/"+strings.Trim(cfg.prefixURL.Path, "/"), "/")
+	pages := make(map[string]vanityImport)
+	for _, imprt := range append(imports[:len(imports):len(imports)], cfg.branchVariants(imports)...) {
+		pages[urlPath(imprt, cfg.prefix)] = imprt
+	}
+	icons := make(map[string]string)
+	for _, file := range []string{cfg.favicon, cfg.appleTouchIcon} {
+		if file != "" {
+			icons[cfg.iconPath(file)] = file
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := path.Clean(r.URL.Path)
+		if file, ok := icons[p]; ok {
+			http.ServeFile(w, r, file)
+			return
+		}
+		if p != base && !strings.HasPrefix(p, base+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		p = "/" + strings.TrimPrefix(strings.TrimPrefix(p, base), "/")
+
+		for {
+			if imprt, ok := pages[p]; ok {
+				html, err := cfg.renderPage(r.Context(), imprt)
+				if err != nil {
+					warnf("", "rendering %s: %v", imprt.Import, err)
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write(html)
+				return
+			}
+			if p == "/" {
+				break
+			}
+			p = path.Dir(p)
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// serveInsecure serves the generated files in cfg.out over plain HTTP at
+// addr until ctx is done, for testing a site locally. Requests are mapped
+// to pages the way GitHub Pages does, /tftp is served from tftp.html.
+func serveInsecure(ctx context.Context, cfg *config, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: siteHandler(cfg.out, cfg.prefixURL.Path)}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	host := cfg.prefixURL.Host
+	logf("Serving %s over HTTP at http://%s\n", cfg.out, ln.Addr())
+	logf("To test with the go tool, resolve %s to this address and set GOINSECURE=%s GOPROXY=direct GONOSUMDB=%s\n", host, host, host)
+
+	err = srv.Serve(ln)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// siteHandler serves the files in dir for a site rooted at the URL path
+// base. Paths without an extension are served from the .html file of the
+// same name, and directories from their index.html.
+func siteHandler(dir, base string) http.Handler {
+	base = "/" + strings.Trim(base, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := path.Clean(r.URL.Path)
+		if p != base && !strings.HasPrefix(p, strings.TrimSuffix(base, "/")+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		p = strings.TrimPrefix(p, strings.TrimSuffix(base, "/"))
+
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			name = filepath.Join(name, "index.html")
+		} else if path.Ext(p) == "" {
+			name += ".html"
+		}
+
+		if info, err := os.Stat(name); err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, name)
+	})
+}
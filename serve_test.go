@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestImportHandler(t *testing.T) {
+	dir := t.TempDir()
+	favicon := filepath.Join(dir, "favicon.ico")
+	if err := ioutil.WriteFile(favicon, []byte("icon"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.favicon = favicon
+	imports := []vanityImport{
+		{Import: "pack.ag", RepoURL: "https://github.com/vcabbage/root", Branch: "master"},
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+	}
+	srv := httptest.NewServer(importHandler(cfg, imports))
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{path: "/?go-get=1", status: http.StatusOK, want: `content="pack.ag git https://github.com/vcabbage/root"`},
+		{path: "/tftp?go-get=1", status: http.StatusOK, want: `content="pack.ag/tftp git https://github.com/vcabbage/tftp"`},
+		{path: "/tftp/netascii", status: http.StatusOK, want: `content="pack.ag/tftp git https://github.com/vcabbage/tftp"`},
+		// A package without its own page falls back to the root.
+		{path: "/amqp", status: http.StatusOK, want: `content="pack.ag git https://github.com/vcabbage/root"`},
+		{path: "/tftp", status: http.StatusOK, want: `<link rel="icon" href="/favicon.ico">`},
+		{path: "/favicon.ico", status: http.StatusOK, want: "icon"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET %s = %d %s, want %d containing %s", tt.path, resp.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestImportHandlerNotFound(t *testing.T) {
+	cfg := testConfig("example.com/go")
+	cfg.pageTmpl = vanity.PageTemplate
+	imports := []vanityImport{
+		{Import: "example.com/go/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+	}
+	srv := httptest.NewServer(importHandler(cfg, imports))
+	defer srv.Close()
+
+	for _, p := range []string{"/", "/go", "/go/amqp", "/tftp"} {
+		resp, err := http.Get(srv.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", p, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+}
+
+func TestSiteHandler(t *testing.T) {
+	for _, layout := range []string{layoutFile, layoutDir} {
+		t.Run(layout, func(t *testing.T) {
+			cfg := testConfig("example.com/go")
+			cfg.pageTmpl = vanity.PageTemplate
+			cfg.layout = layout
+			cfg.rootBehavior = rootIndex
+			cfg.out = t.TempDir()
+			imports := []vanityImport{
+				{Import: "example.com/go/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+			}
+			if _, err := generate(context.Background(), cfg, cfg.out, imports); err != nil {
+				t.Fatal(err)
+			}
+
+			// The site serveInsecure serves over plain HTTP.
+			srv := httptest.NewServer(siteHandler(cfg.out, cfg.prefixURL.Path))
+			defer srv.Close()
+			if !strings.HasPrefix(srv.URL, "http://") {
+				t.Fatalf("serving at %s, want plain HTTP", srv.URL)
+			}
+
+			tests := []struct {
+				path   string
+				status int
+				want   string
+			}{
+				{path: "/go/tftp?go-get=1", status: http.StatusOK, want: `content="example.com/go/tftp git https://github.com/vcabbage/tftp"`},
+				{path: "/go/", status: http.StatusOK, want: `<a href="https://example.com/go/tftp">`},
+				{path: "/go/amqp?go-get=1", status: http.StatusNotFound},
+				{path: "/tftp?go-get=1", status: http.StatusNotFound},
+			}
+			for _, tt := range tests {
+				resp, err := http.Get(srv.URL + tt.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+					t.Errorf("GET %s = %d %s, want %d containing %s", tt.path, resp.StatusCode, body, tt.status, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+// sparsePatterns are the sparse checkout patterns of the files needed to
+// find vanity imports, which is all go list reads. A checked in go.work
+// is kept since go list uses the workspace, as it does in a full checkout.
+var sparsePatterns = []string{
+	"*.go",
+	"go.mod",
+	"go.sum",
+	"go.work",
+	"go.work.sum",
+	"/" + vanity.OverridesFile,
+	"/" + vanity.IgnoreFile,
+}
+
+// sparseCheckout checks out the files matching sparsePatterns in the
+// clone at dir of the repository at url, made with --no-checkout. The
+// clone is partial where the server supports it, so the blobs of other
+// files are never downloaded. Every file is checked out if a sparse
+// checkout can't be set up.
+func sparseCheckout(ctx context.Context, cfg *config, url, dir string) error {
+	git := func(args ...string) error {
+		cmd := commandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = cfg.gitEnv()
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return gitError(fmt.Errorf("git %s: %v: %s", args[0], err, bytes.TrimSpace(out)), out)
+		}
+		return nil
+	}
+
+	// Writing the patterns directly works with versions of git which
+	// predate the sparse-checkout command.
+	err := git("config", "core.sparseCheckout", "true")
+	if err == nil {
+		patterns := strings.Join(sparsePatterns, "\n") + "\n"
+		err = ioutil.WriteFile(filepath.Join(dir, ".git", "info", "sparse-checkout"), []byte(patterns), 0600)
+	}
+	if err == nil {
+		if err = git("read-tree", "-mu", "HEAD"); err == nil {
+			return nil
+		}
+	}
+
+	warnf(url, "sparse checkout failed, checking out every file: %v", err)
+	if err := git("config", "core.sparseCheckout", "false"); err != nil {
+		return err
+	}
+	return git("read-tree", "-mu", "HEAD")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// downloadTarball extracts the tarball of ref of the GitHub repository
+// owner/repo into dir, the default branch if ref is empty, retrying
+// failures with cfg.retry. Unlike clone, git isn't required.
+func downloadTarball(ctx context.Context, gh *github.Client, cfg *config, owner, repo, ref, dir string) error {
+	var link *url.URL
+	err := cfg.retry.do(ctx, func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		link, resp, err = gh.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: ref})
+		if err != nil && resp != nil {
+			return archiveLinkError(resp, err)
+		}
+		return err
+	})
+	if err != nil {
+		// Rate limiting is still reported by isRateLimited.
+		return fmt.Errorf("getting tarball link: %w", err)
+	}
+
+	return cfg.retry.do(ctx, func() error {
+		// Start each attempt from an empty directory.
+		if err := emptyDir(dir); err != nil {
+			return err
+		}
+		return fetchTarball(ctx, cfg, link.String(), dir)
+	})
+}
+
+// archiveLinkError returns the error of a request for an archive link
+// which failed with resp as the type other API requests fail with, so that
+// it's retried and rate limits are detected. The vendored GetArchiveLink
+// only reports the unexpected status.
+func archiveLinkError(resp *github.Response, err error) error {
+	limited := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if limited && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+		return &github.RateLimitError{Rate: resp.Rate, Response: resp.Response, Message: err.Error()}
+	}
+	return &github.ErrorResponse{Response: resp.Response, Message: err.Error()}
+}
+
+// fetchTarball extracts the tarball at link into dir.
+func fetchTarball(ctx context.Context, cfg *config, link, dir string) error {
+	// The link is only valid for a short time and includes a token
+	// for private repositories, so no authorization is needed.
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cfg.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		// The error may contain the link, it's redacted when logged.
+		return &transientError{err: fmt.Errorf("downloading tarball: %v", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("downloading tarball: unexpected status %s", resp.Status)
+		if transientStatus(resp.StatusCode) {
+			return &transientError{err: err}
+		}
+		return err
+	}
+
+	if err := extractTarball(resp.Body, dir); err != nil {
+		err = fmt.Errorf("extracting tarball: %v", err)
+		if ctx.Err() == nil {
+			// The download was cut short.
+			return &transientError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// extractTarball extracts the gzipped tar archive r into dir. GitHub
+// places the files under a single "<owner>-<repo>-<sha>" directory,
+// which is stripped. Only regular files and directories are extracted.
+func extractTarball(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Strip the top level directory.
+		name := hdr.Name
+		i := strings.Index(name, "/")
+		if i < 0 {
+			continue
+		}
+		name = strings.Trim(name[i+1:], "/")
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside of the archive", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return err
+			}
+			if err := writeTarFile(tr, path, hdr.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeTarFile writes the contents of the current file in tr to path.
+func writeTarFile(tr *tar.Reader, path string, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// apiMajorBranches is the equivalent of majorBranches which lists the
+// branches of the GitHub repository owner/repo through the API.
+func apiMajorBranches(ctx context.Context, gh *github.Client, cfg *config, owner, repo string) ([]string, error) {
+	var (
+		branches []string
+		opt      = &github.ListOptions{PerPage: cfg.perPage}
+	)
+	for {
+		var (
+			page []*github.Branch
+			resp *github.Response
+		)
+		err := cfg.retry.do(ctx, func() (err error) {
+			page, resp, err = gh.Repositories.ListBranches(ctx, owner, repo, opt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range page {
+			if majorBranchRE.MatchString(branch.GetName()) {
+				branches = append(branches, branch.GetName())
+			}
+		}
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTarball returns a gzipped tarball of files in a top level directory,
+// as GitHub serves them.
+func testTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		hdr := &tar.Header{Name: "vcabbage-a-0123abc/" + name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadTarball(t *testing.T) {
+	tarball := testTarball(t, map[string]string{"a.go": "package a // import \"pack.ag/a\"\n"})
+
+	tests := []struct {
+		name          string
+		linkStatus    []int // status of each request for the link, then 302
+		downloadFails int   // downloads failing with 502 before succeeding
+		wantLinkCalls int
+		wantErr       bool
+		wantRateLimit bool
+	}{
+		{name: "success", wantLinkCalls: 1},
+		{name: "server error retried", linkStatus: []int{http.StatusBadGateway}, wantLinkCalls: 2},
+		{name: "not found not retried", linkStatus: []int{http.StatusNotFound}, wantLinkCalls: 1, wantErr: true},
+		{name: "rate limited", linkStatus: []int{http.StatusForbidden}, wantLinkCalls: 1, wantErr: true, wantRateLimit: true},
+		{name: "download retried", downloadFails: 2, wantLinkCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var linkCalls, downloads int
+			mux := http.NewServeMux()
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			mux.HandleFunc("/api/v3/repos/vcabbage/a/tarball", func(w http.ResponseWriter, r *http.Request) {
+				linkCalls++
+				if linkCalls <= len(tt.linkStatus) {
+					status := tt.linkStatus[linkCalls-1]
+					if status == http.StatusForbidden {
+						w.Header().Set("X-RateLimit-Remaining", "0")
+						w.Header().Set("X-RateLimit-Reset", "4102444800")
+					}
+					w.WriteHeader(status)
+					w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+					return
+				}
+				http.Redirect(w, r, srv.URL+"/download", http.StatusFound)
+			})
+			mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+				downloads++
+				if downloads <= tt.downloadFails {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.Write(tarball)
+			})
+
+			cfg := &config{
+				githubURL: srv.URL,
+				retry:     retryPolicy{attempts: 3, baseDelay: time.Millisecond, maxDelay: time.Millisecond},
+			}
+			gh, err := newGitHubClient(cfg, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			err = downloadTarball(context.Background(), gh, cfg, "vcabbage", "a", "", dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadTarball() = %v, want error %t", err, tt.wantErr)
+			}
+			if linkCalls != tt.wantLinkCalls {
+				t.Errorf("tarball link requested %d times, want %d", linkCalls, tt.wantLinkCalls)
+			}
+			if isRateLimited(err) != tt.wantRateLimit {
+				t.Errorf("isRateLimited(%v) = %t, want %t", err, !tt.wantRateLimit, tt.wantRateLimit)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := ioutil.ReadFile(filepath.Join(dir, "a.go")); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// topicPrefix marks search entries which find GitHub repositories by
+// topic. The whole entry is the search query, so other qualifiers can
+// follow the topic, such as "topic:vanity user:vcabbage".
+const topicPrefix = "topic:"
+
+// searchAllRepositories returns every page of the repositories matching
+// the search entry, which is prefixed with topicPrefix. Forks are only
+// searched with -include-forks. GitHub returns at most 1000 results.
+func searchAllRepositories(ctx context.Context, gh repoLister, cfg *config, entry string) ([]*repository, error) {
+	query := entry
+	if cfg.includeForks {
+		query += " fork:true"
+	}
+
+	opt := &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: cfg.perPage},
+	}
+	var all []*repository
+	for {
+		var (
+			repos []*repository
+			resp  *github.Response
+		)
+		err := cfg.retry.do(ctx, func() (err error) {
+			repos, resp, err = gh.searchRepositories(ctx, query, opt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
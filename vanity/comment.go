@@ -0,0 +1,119 @@
+package vanity
+
+import (
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FindImportComment returns the import comment of the package in dir by
+// parsing the package clause of each of its Go source files. Test files
+// and files ignored by the go tool are skipped, and only a comment on the
+// same line as the package clause is considered, matching the go tool's
+// own import comment rules.
+//
+// An empty string is returned if no file has an import comment.
+func FindImportComment(dir string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	fset := token.NewFileSet()
+	for _, info := range infos {
+		if info.IsDir() || !IsGoSource(info.Name()) {
+			continue
+		}
+
+		clause, err := ParsePackageClause(fset, filepath.Join(dir, info.Name()), nil)
+		if err != nil {
+			continue
+		}
+		if clause.ImportPath != "" {
+			return clause.ImportPath, nil
+		}
+	}
+	return "", nil
+}
+
+// IsGoSource reports whether the file name is a non-test Go source
+// file which isn't ignored by the go tool.
+func IsGoSource(name string) bool {
+	return strings.HasSuffix(name, ".go") &&
+		!strings.HasSuffix(name, "_test.go") &&
+		!strings.HasPrefix(name, "_") &&
+		!strings.HasPrefix(name, ".")
+}
+
+// PackageClause is the information parsed from a file's package clause.
+type PackageClause struct {
+	Name       string
+	ImportPath string // from the import comment, if any
+	Synopsis   string // from the package documentation, if any
+}
+
+// ParsePackageClause parses the package clause of the Go source file
+// filename. If src is nil the file is read from disk.
+func ParsePackageClause(fset *token.FileSet, filename string, src interface{}) (PackageClause, error) {
+	f, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return PackageClause{}, err
+	}
+
+	clause := PackageClause{
+		Name:     f.Name.Name,
+		Synopsis: doc.Synopsis(f.Doc.Text()),
+	}
+
+	line := fset.Position(f.Name.End()).Line
+	for _, group := range f.Comments {
+		c := group.List[0]
+		if c.Pos() < f.Name.End() || fset.Position(c.Pos()).Line != line {
+			continue
+		}
+
+		if path, ok := parseImportComment(c.Text); ok {
+			clause.ImportPath = path
+		}
+		break
+	}
+	return clause, nil
+}
+
+// parseImportComment returns the import path from a comment of the form
+// `// import "path"` or `/* import "path" */`.
+func parseImportComment(comment string) (string, bool) {
+	switch {
+	case strings.HasPrefix(comment, "//"):
+		comment = comment[2:]
+	case strings.HasPrefix(comment, "/*"):
+		comment = strings.TrimSuffix(comment[2:], "*/")
+	}
+
+	comment = strings.TrimSpace(comment)
+	if !strings.HasPrefix(comment, "import") {
+		return "", false
+	}
+	comment = strings.TrimSpace(strings.TrimPrefix(comment, "import"))
+
+	path, err := strconv.Unquote(comment)
+	if err != nil || CleanImportPath(path) == "" {
+		return "", false
+	}
+	return CleanImportPath(path), true
+}
+
+// CleanImportPath removes trailing slashes from path, which some tools
+// write in import comments, and lower cases its host. Hosts aren't case
+// sensitive, and the go command requires them to be lower case.
+func CleanImportPath(path string) string {
+	path = strings.TrimRight(path, "/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		return strings.ToLower(path[:i]) + path[i:]
+	}
+	return strings.ToLower(path)
+}
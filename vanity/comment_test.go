@@ -0,0 +1,154 @@
+package vanity
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestFindImportComment(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "import comment",
+			files: map[string]string{"tftp.go": "package tftp // import \"pack.ag/tftp\"\n"},
+			want:  "pack.ag/tftp",
+		},
+		{
+			name: "in a later file",
+			files: map[string]string{
+				"a.go": "package tftp\n",
+				"b.go": "package tftp // import \"pack.ag/tftp\"\n",
+			},
+			want: "pack.ag/tftp",
+		},
+		{
+			name: "test file",
+			files: map[string]string{
+				"tftp.go":      "package tftp\n",
+				"tftp_test.go": "package tftp // import \"github.com/vcabbage/tftp\"\n",
+			},
+		},
+		{
+			name: "ignored files",
+			files: map[string]string{
+				"_tftp.go": "package tftp // import \"github.com/vcabbage/tftp\"\n",
+				".tftp.go": "package tftp // import \"github.com/vcabbage/tftp\"\n",
+			},
+		},
+		{
+			name: "unparsable file",
+			files: map[string]string{
+				"a.go": "<html></html>\n",
+				"b.go": "package tftp // import \"pack.ag/tftp\"\n",
+			},
+			want: "pack.ag/tftp",
+		},
+		{
+			name:  "no import comment",
+			files: map[string]string{"tftp.go": "package tftp\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.files)
+			got, err := FindImportComment(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FindImportComment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsGoSource(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "tftp.go", want: true},
+		{name: "tftp_linux.go", want: true},
+		{name: "tftp_test.go"},
+		{name: "_tftp.go"},
+		{name: ".tftp.go"},
+		{name: "tftp.s"},
+		{name: "go.mod"},
+	}
+	for _, tt := range tests {
+		if got := IsGoSource(tt.name); got != tt.want {
+			t.Errorf("IsGoSource(%q) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCleanImportPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "pack.ag/tftp", want: "pack.ag/tftp"},
+		{path: "Pack.AG/tftp", want: "pack.ag/tftp"},
+		{path: "pack.ag/TFTP", want: "pack.ag/TFTP"},
+		{path: "Pack.AG", want: "pack.ag"},
+		{path: "pack.ag/tftp/", want: "pack.ag/tftp"},
+		{path: "Pack.AG/tftp//", want: "pack.ag/tftp"},
+		{path: "Pack.AG/", want: "pack.ag"},
+		{path: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := CleanImportPath(tt.path); got != tt.want {
+			t.Errorf("CleanImportPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePackageClause(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    PackageClause
+		wantErr bool
+	}{
+		{
+			name: "line comment",
+			src:  "// Package tftp implements TFTP.\npackage tftp // import \"pack.ag/tftp\"\n",
+			want: PackageClause{Name: "tftp", ImportPath: "pack.ag/tftp", Synopsis: "Package tftp implements TFTP."},
+		},
+		{
+			name: "block comment",
+			src:  "package tftp /* import \"pack.ag/tftp\" */\n",
+			want: PackageClause{Name: "tftp", ImportPath: "pack.ag/tftp"},
+		},
+		{
+			name: "no import comment",
+			src:  "package tftp // TFTP\n",
+			want: PackageClause{Name: "tftp"},
+		},
+		{
+			name: "comment on the next line",
+			src:  "package tftp\n// import \"pack.ag/tftp\"\n",
+			want: PackageClause{Name: "tftp"},
+		},
+		{
+			name:    "not go",
+			src:     "<html></html>\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePackageClause(token.NewFileSet(), "tftp.go", tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePackageClause() = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePackageClause() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
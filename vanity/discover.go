@@ -0,0 +1,423 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Config selects the packages which get vanity imports and how go list
+// finds them.
+type Config struct {
+	// Prefixes are the vanity import path prefixes, such as pack.ag.
+	// Packages outside of all of them are skipped.
+	Prefixes []string
+
+	// CaseInsensitive matches import paths to Prefixes regardless of
+	// case.
+	CaseInsensitive bool
+
+	// Match, if set, skips packages whose import paths it doesn't match.
+	Match *regexp.Regexp
+
+	ModuleOnly   bool // use module paths, ignoring import comments
+	SkipInternal bool // skip packages with an internal element
+	Commands     bool // include main packages
+
+	// Tags, GOOS, and GOARCH are passed to go list, and CGO enables
+	// cgo, selecting the files of a cgo build. Packages made only of
+	// cgo files are found either way.
+	Tags   string
+	GOOS   string
+	GOARCH string
+	CGO    bool
+
+	// Repos are the checkouts Discover scans.
+	Repos []Repo
+
+	// OnSkip, if set, is called by Discover with each package which
+	// doesn't get a vanity import and the URL of its repository. A
+	// repository skipped by its IgnoreFile is reported with its URL as
+	// the Path.
+	OnSkip func(repoURL string, pkg Skipped)
+}
+
+// Repo is a checkout of a repository.
+type Repo struct {
+	URL    string // RepoURL of the imports found
+	Branch string // the branch checked out
+	Dir    string
+
+	// Roots maps subdirectories of the repository to the import
+	// prefix of the packages within them, see ApplyRoots.
+	Roots map[string]string
+}
+
+// Skipped is a package found while scanning which doesn't get a vanity
+// import.
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// RepoError is an error scanning the checkout of the repository at URL.
+type RepoError struct {
+	URL string
+	Err error
+}
+
+func (e *RepoError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// Discover returns the vanity imports of the packages in each of
+// cfg.Repos. The packages declared by a checkout's OverridesFile are
+// used if it has one, otherwise those of every module within it are
+// listed, less those excluded by its IgnoreFile. Errors are a
+// *RepoError.
+func Discover(ctx context.Context, cfg Config) ([]Import, error) {
+	var imports []Import
+	for _, repo := range cfg.Repos {
+		found, err := discoverRepo(ctx, cfg, repo)
+		if err != nil {
+			return nil, &RepoError{URL: repo.URL, Err: err}
+		}
+		imports = append(imports, found...)
+	}
+	return imports, nil
+}
+
+// discoverRepo returns the vanity imports of the packages in the
+// checkout of repo.
+func discoverRepo(ctx context.Context, cfg Config, repo Repo) ([]Import, error) {
+	skip := func(skipped []Skipped) {
+		if cfg.OnSkip == nil {
+			return
+		}
+		for _, pkg := range skipped {
+			cfg.OnSkip(repo.URL, pkg)
+		}
+	}
+
+	ignore, err := ReadIgnore(repo.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if ignore != nil && ignore.Skip {
+		skip([]Skipped{{Path: repo.URL, Reason: "skipped by " + IgnoreFile}})
+		return nil, nil
+	}
+
+	var imports []Import
+	overrides, err := ReadOverrides(repo.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		var skipped []Skipped
+		imports, skipped, err = overrides.Imports(cfg, repo.URL)
+		if err != nil {
+			return nil, err
+		}
+		skip(skipped)
+	} else {
+		moduleDirs, err := FindModules(repo.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, moduleDir := range moduleDirs {
+			packages, skipped, err := ListPackages(ctx, cfg, repo.Dir, moduleDir, repo.URL)
+			if err != nil {
+				return nil, err
+			}
+			imports = append(imports, packages...)
+			skip(skipped)
+		}
+	}
+
+	imports, ignored := ignore.Filter(imports)
+	skip(ignored)
+
+	ApplyRoots(imports, repo.Roots)
+	for i := range imports {
+		imports[i].Branch = repo.Branch
+	}
+	return imports, nil
+}
+
+// FindModules returns the directories within root that go list should be
+// run from. When root is a module, nested modules (such as major version
+// subdirectories) are included since ./... doesn't descend into them.
+func FindModules(root string) ([]string, error) {
+	dirs := []string{root}
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		return dirs, nil
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == root {
+			return nil
+		}
+
+		name := info.Name()
+		if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// killWaitDelay is how long to wait for the output of a killed go list
+// to be closed, since its children may keep it open until they exit.
+const killWaitDelay = time.Second
+
+// ListPackages runs go list in moduleDir, one of the FindModules of
+// root, returning the vanity imports of the packages within it and the
+// packages which were skipped. Package directories are made relative to
+// root, the checkout of the repository at repoURL. Dependencies aren't
+// resolved, so packages are found even if they don't build.
+func ListPackages(ctx context.Context, cfg Config, root, moduleDir, repoURL string) ([]Import, []Skipped, error) {
+	var (
+		imports []Import
+		skipped []Skipped
+	)
+
+	listed, err := goList(ctx, cfg, moduleDir, cfg.CGO)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cfg.CGO {
+		// Packages made only of cgo files aren't listed with cgo
+		// disabled, so they're found by listing again with it
+		// enabled. -find doesn't run cgo, so this doesn't need a C
+		// toolchain either.
+		withCgo, err := goList(ctx, cfg, moduleDir, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, pkg := range withCgo {
+			if len(pkg.GoFiles) == 0 && len(pkg.CgoFiles) > 0 {
+				listed = append(listed, pkg)
+			}
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(moduleDir, "go.mod"))
+	hasModule := err == nil
+
+	for _, pkg := range listed {
+		if pkg.Error != nil && !pkg.embedError() {
+			return nil, nil, fmt.Errorf("%s: %s", pkg.ImportPath, pkg.Error.Err)
+		}
+
+		importPath := CleanImportPath(pkg.ImportComment)
+		if importPath == "" && !cfg.ModuleOnly {
+			// go list doesn't report import comments in module mode.
+			importPath, err = FindImportComment(pkg.Dir)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if cfg.ModuleOnly || importPath == "" {
+			// In module mode the import path is the module path
+			// followed by the package's directory.
+			importPath = ""
+			if hasModule {
+				importPath = pkg.ImportPath
+			}
+		}
+
+		if reason := cfg.SkipReason(importPath, pkg.Name); reason != "" {
+			if importPath == "" {
+				importPath = pkg.ImportPath
+			}
+			skipped = append(skipped, Skipped{Path: importPath, Reason: reason})
+			continue
+		}
+
+		dir, err := filepath.EvalSymlinks(pkg.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pathLen := 0
+		if dir != root {
+			dir = filepath.ToSlash(strings.TrimLeft(strings.TrimPrefix(dir, root), "/\\"))
+			pathLen = len(strings.Split(dir, "/"))
+
+			// Only the directories within the repository are
+			// checked, it may itself be cloned into a directory
+			// named testdata.
+			if reason := ignoredSegment(dir); reason != "" {
+				if importPath == "" {
+					importPath = pkg.ImportPath
+				}
+				skipped = append(skipped, Skipped{Path: importPath, Reason: reason})
+				continue
+			}
+		}
+
+		imports = append(imports, Import{
+			Import:   importPath,
+			RepoURL:  repoURL,
+			Synopsis: pkg.Doc,
+			PathLen:  pathLen,
+			Command:  pkg.Name == "main",
+			File:     firstFile(append(pkg.GoFiles, pkg.CgoFiles...)),
+		})
+	}
+
+	return imports, skipped, nil
+}
+
+// goList returns the packages go list finds in moduleDir, with cgo
+// enabled if cgo is set.
+func goList(ctx context.Context, cfg Config, moduleDir string, cgo bool) ([]listedPackage, error) {
+	// With -e, go list reports the errors of each package rather than
+	// failing, so packages whose embedded files weren't checked out
+	// are still listed.
+	args := []string{"list", "-e", "-find", "-json"}
+	if cfg.Tags != "" {
+		args = append(args, "-tags="+cfg.Tags)
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, "./...")...)
+	cmd.WaitDelay = killWaitDelay
+	cmd.Dir = moduleDir
+	cmd.Env = os.Environ()
+	// The go command disables cgo by default without a C toolchain,
+	// so it's always set explicitly.
+	if cgo {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED=1")
+	} else {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED=0")
+	}
+	if cfg.GOOS != "" {
+		cmd.Env = append(cmd.Env, "GOOS="+cfg.GOOS)
+	}
+	if cfg.GOARCH != "" {
+		cmd.Env = append(cmd.Env, "GOARCH="+cfg.GOARCH)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	var listed []listedPackage
+	dec := json.NewDecoder(out)
+	for {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			cmd.Wait()
+			return nil, fmt.Errorf("decoding go list output: %v", err)
+		}
+		listed = append(listed, pkg)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return listed, nil
+}
+
+// firstFile returns the first of files, or an empty string if there are
+// none.
+func firstFile(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return files[0]
+}
+
+// listedPackage holds the fields of a package reported by go list -json
+// which are used to find vanity imports.
+type listedPackage struct {
+	ImportComment string
+	Name          string
+	ImportPath    string
+	Dir           string
+	Doc           string
+	GoFiles       []string
+	CgoFiles      []string
+	EmbedPatterns []string
+	Error         *struct {
+		Err string
+	}
+}
+
+// embedError reports whether the error of p is that an embedded file
+// wasn't found, which only prevents building the package.
+func (p *listedPackage) embedError() bool {
+	for _, pattern := range p.EmbedPatterns {
+		if strings.HasPrefix(p.Error.Err, "pattern "+pattern+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipReason returns why the package named name with the import path
+// importPath doesn't get a vanity import, or an empty string if it does.
+func (cfg Config) SkipReason(importPath, name string) string {
+	switch {
+	case importPath == "" && cfg.ModuleOnly:
+		return "no go.mod"
+	case importPath == "":
+		return "no import comment"
+	case MatchPrefix(cfg.Prefixes, importPath, cfg.CaseInsensitive) == "":
+		return "non-matching prefix"
+	case cfg.Match != nil && !cfg.Match.MatchString(importPath):
+		return "non-matching pattern"
+	case cfg.SkipInternal && isInternal(importPath):
+		return "internal"
+	case name == "main" && !cfg.Commands:
+		return "main"
+	}
+	return ""
+}
+
+// ignoredSegment returns "vendor" or "testdata" if the slash separated
+// dir has an element with that name, or an empty string otherwise. The
+// go command ignores testdata directories, but may still list vendored
+// copies of a repository's own packages.
+func ignoredSegment(dir string) string {
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "vendor" || elem == "testdata" {
+			return elem
+		}
+	}
+	return ""
+}
+
+// isInternal reports whether importPath contains an internal element.
+func isInternal(importPath string) bool {
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package vanity
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListPackagesEmbed(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod": "module pack.ag/embed\n\ngo 1.16\n",
+		// data.txt isn't checked out by -sparse.
+		"embed.go":   "package embed\n\nimport _ \"embed\"\n\n//go:embed data.txt\nvar data string\n",
+		"sub/sub.go": "package sub\n",
+	})
+
+	cfg := Config{Prefixes: []string{"pack.ag"}, ModuleOnly: true}
+	imports, _, err := ListPackages(context.Background(), cfg, dir, dir, "https://github.com/vcabbage/embed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, imprt := range imports {
+		got = append(got, imprt.Import)
+	}
+	want := []string{"pack.ag/embed", "pack.ag/embed/sub"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imports = %q, want %q", got, want)
+	}
+}
+
+func TestListPackagesCgo(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":           "module pack.ag/cgo\n",
+		"cgo.go":           "package cgo\n\n// #include <stdlib.h>\nimport \"C\"\n",
+		"tags/nocgo.go":    "//go:build !cgo\n\npackage tags\n",
+		"tags/cgo.go":      "//go:build cgo\n\npackage tags\n",
+		"mixed/mixed.go":   "package mixed\n",
+		"mixed/mixed_c.go": "package mixed\n\nimport \"C\"\n",
+	})
+
+	tests := []struct {
+		cgo  bool
+		want map[string]string // first file by import path
+	}{
+		{cgo: false, want: map[string]string{"pack.ag/cgo": "cgo.go", "pack.ag/cgo/mixed": "mixed.go", "pack.ag/cgo/tags": "nocgo.go"}},
+		{cgo: true, want: map[string]string{"pack.ag/cgo": "cgo.go", "pack.ag/cgo/mixed": "mixed.go", "pack.ag/cgo/tags": "cgo.go"}},
+	}
+	for _, tt := range tests {
+		cfg := Config{Prefixes: []string{"pack.ag"}, ModuleOnly: true, CGO: tt.cgo}
+		imports, _, err := ListPackages(context.Background(), cfg, dir, dir, "https://github.com/vcabbage/cgo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, imprt := range imports {
+			got[imprt.Import] = imprt.File
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cgo %t: imports = %v, want %v", tt.cgo, got, tt.want)
+		}
+	}
+}
+
+func TestListPackagesTags(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":                 "module pack.ag/tags\n",
+		"tags.go":                "package tags\n",
+		"appengine/appengine.go": "//go:build appengine\n\npackage appengine\n",
+	})
+
+	tests := []struct {
+		tags string
+		want []string
+	}{
+		{tags: "", want: []string{"pack.ag/tags"}},
+		{tags: "appengine", want: []string{"pack.ag/tags", "pack.ag/tags/appengine"}},
+	}
+	for _, tt := range tests {
+		cfg := Config{Prefixes: []string{"pack.ag"}, ModuleOnly: true, Tags: tt.tags}
+		imports, _, err := ListPackages(context.Background(), cfg, dir, dir, "https://github.com/vcabbage/tags")
+		if err != nil {
+			t.Fatalf("tags %q: %v", tt.tags, err)
+		}
+		var got []string
+		for _, imprt := range imports {
+			got = append(got, imprt.Import)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tags %q: imports = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestListPackagesMatch(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The module path reported by go list differs from the import
+	// comments, which -match applies to.
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":                 "module github.com/vcabbage/tftp\n",
+		"tftp.go":                "package tftp // import \"pack.ag/tftp\"\n",
+		"netascii/netascii.go":   "package netascii // import \"pack.ag/tftp/netascii\"\n",
+		"internal/x/x.go":        "package x // import \"pack.ag/tftp/internal/x\"\n",
+		"cmd/tftpd/tftpd.go":     "package main // import \"pack.ag/tftp/cmd/tftpd\"\n",
+		"examples/example.go":    "package examples // import \"pack.ag/examples\"\n",
+		"nocomment/nocomment.go": "package nocomment\n",
+	})
+
+	cfg := Config{Prefixes: []string{"pack.ag"}, Match: regexp.MustCompile(`^pack\.ag/tftp(/|$)`), SkipInternal: true}
+	imports, skipped, err := ListPackages(context.Background(), cfg, dir, dir, "https://github.com/vcabbage/tftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, imprt := range imports {
+		got = append(got, imprt.Import)
+	}
+	want := []string{"pack.ag/tftp", "pack.ag/tftp/netascii"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imports = %q, want %q", got, want)
+	}
+
+	gotSkipped := make(map[string]string)
+	for _, s := range skipped {
+		gotSkipped[s.Path] = s.Reason
+	}
+	wantSkipped := map[string]string{
+		"pack.ag/examples":                   "non-matching pattern",
+		"pack.ag/tftp/cmd/tftpd":             "main",
+		"pack.ag/tftp/internal/x":            "internal",
+		"github.com/vcabbage/tftp/nocomment": "non-matching prefix",
+	}
+	if !reflect.DeepEqual(gotSkipped, wantSkipped) {
+		t.Errorf("skipped = %v, want %v", gotSkipped, wantSkipped)
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	tests := []struct {
+		cfg        Config
+		importPath string
+		name       string
+		want       string
+	}{
+		{importPath: "pack.ag/a", name: "a", want: ""},
+		{importPath: "", name: "a", want: "no import comment"},
+		{cfg: Config{ModuleOnly: true}, importPath: "", name: "a", want: "no go.mod"},
+		{importPath: "example.com/a", name: "a", want: "non-matching prefix"},
+		{importPath: "pack.ag/a/internal/b", name: "b", want: ""},
+		{cfg: Config{SkipInternal: true}, importPath: "pack.ag/a/internal/b", name: "b", want: "internal"},
+		{cfg: Config{SkipInternal: true}, importPath: "pack.ag/a/internals", name: "internals", want: ""},
+		{importPath: "pack.ag/a/cmd/b", name: "main", want: "main"},
+		{cfg: Config{Commands: true}, importPath: "pack.ag/a/cmd/b", name: "main", want: ""},
+	}
+	for _, tt := range tests {
+		tt.cfg.Prefixes = []string{"pack.ag"}
+		if got := tt.cfg.SkipReason(tt.importPath, tt.name); got != tt.want {
+			t.Errorf("SkipReason(%q, %q) = %q, want %q", tt.importPath, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Setenv("GO111MODULE", "on")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"tftp/go.mod":               "module pack.ag/tftp\n",
+		"tftp/tftp.go":              "// Package tftp implements TFTP.\npackage tftp\n",
+		"tftp/netascii/netascii.go": "package netascii\n",
+		"tftp/cmd/tftpd/main.go":    "package main\n",
+		"tftp/" + IgnoreFile:        "pack.ag/tftp/netascii\n",
+		"amqp/go.mod":               "module pack.ag/amqp\n",
+		"amqp/amqp.go":              "package amqp\n",
+		"amqp/" + IgnoreFile:        "skip\n",
+		"sctp/" + OverridesFile:     `{"packages": [{"import": "pack.ag/sctp", "dir": "go"}, {"import": "github.com/vcabbage/sctp/x", "dir": "x"}]}`,
+	})
+
+	var skipped []Skipped
+	imports, err := Discover(context.Background(), Config{
+		Prefixes:   []string{"pack.ag"},
+		ModuleOnly: true,
+		Repos: []Repo{
+			{URL: "https://github.com/vcabbage/tftp", Branch: "master", Dir: filepath.Join(dir, "tftp")},
+			{URL: "https://github.com/vcabbage/amqp", Branch: "master", Dir: filepath.Join(dir, "amqp")},
+			{URL: "https://github.com/vcabbage/sctp", Branch: "main", Dir: filepath.Join(dir, "sctp"), Roots: map[string]string{"go": "pack.ag/sctp"}},
+		},
+		OnSkip: func(repoURL string, pkg Skipped) {
+			skipped = append(skipped, pkg)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Import{{
+		Import:   "pack.ag/tftp",
+		RepoURL:  "https://github.com/vcabbage/tftp",
+		Branch:   "master",
+		Synopsis: "Package tftp implements TFTP.",
+		File:     "tftp.go",
+	}, {
+		Import:  "pack.ag/sctp",
+		RepoURL: "https://github.com/vcabbage/sctp",
+		Branch:  "main",
+		Subdir:  "go",
+	}}
+	if !reflect.DeepEqual(imports, want) {
+		t.Errorf("imports = %+v, want %+v", imports, want)
+	}
+
+	wantSkipped := []Skipped{
+		{Path: "pack.ag/tftp/cmd/tftpd", Reason: "main"},
+		{Path: "pack.ag/tftp/netascii", Reason: "ignored by " + IgnoreFile},
+		{Path: "https://github.com/vcabbage/amqp", Reason: "skipped by " + IgnoreFile},
+		{Path: "github.com/vcabbage/sctp/x", Reason: "non-matching prefix"},
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %+v, want %+v", skipped, wantSkipped)
+	}
+}
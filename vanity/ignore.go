@@ -0,0 +1,92 @@
+package vanity
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the name of the file at the root of a repository which
+// excludes some or all of the repository's packages.
+const IgnoreFile = ".vanityignore"
+
+// Ignore is the contents of an IgnoreFile. Each line is a glob
+// pattern, in the syntax of path.Match, of the import paths to exclude,
+// or "skip" to exclude the whole repository. Blank lines and lines
+// beginning with # are ignored.
+//
+// Example:
+//
+//	# Internal tools don't need pages.
+//	pack.ag/tftp/cmd/*
+//	pack.ag/tftp/internal
+type Ignore struct {
+	Skip     bool // exclude the whole repository
+	patterns []string
+}
+
+// ParseIgnore parses the contents of an IgnoreFile.
+func ParseIgnore(data []byte) (*Ignore, error) {
+	var ig Ignore
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "skip":
+			ig.Skip = true
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", IgnoreFile, n, err)
+		}
+		ig.patterns = append(ig.patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", IgnoreFile, err)
+	}
+	return &ig, nil
+}
+
+// ReadIgnore reads the IgnoreFile in dir. A nil *Ignore is returned
+// if the file doesn't exist.
+func ReadIgnore(dir string) (*Ignore, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseIgnore(data)
+}
+
+// Filter returns the imports which don't match a pattern of ig and the
+// packages skipped because they do.
+func (ig *Ignore) Filter(imports []Import) ([]Import, []Skipped) {
+	if ig == nil || len(ig.patterns) == 0 {
+		return imports, nil
+	}
+
+	var (
+		kept    []Import
+		skipped []Skipped
+	)
+outer:
+	for _, imprt := range imports {
+		for _, pattern := range ig.patterns {
+			if ok, _ := path.Match(pattern, imprt.Import); ok {
+				skipped = append(skipped, Skipped{Path: imprt.Import, Reason: "ignored by " + IgnoreFile})
+				continue outer
+			}
+		}
+		kept = append(kept, imprt)
+	}
+	return kept, skipped
+}
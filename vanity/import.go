@@ -0,0 +1,202 @@
+// Package vanity lists the Go repositories of GitHub users, finds the
+// vanity import paths of the Go packages in checkouts of them, and
+// renders the pages the go command fetches to resolve them.
+//
+// It's the core of the govanity command, which adds other sources of
+// repositories, fetching them, and writing the pages of a site.
+package vanity
+
+import (
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// Import is the vanity import of a package from the repository at
+// RepoURL. Its exported methods are used by page templates.
+type Import struct {
+	Import   string
+	RepoURL  string
+	Branch   string
+	Synopsis string
+	Tags     []string
+	MovedTo  string // new import path of a moved package
+	PathLen  int    // segments of Import within the directory of ImportPrefix
+	Subdir   string // directory of ImportPrefix within the repository
+	Command  bool   // the package is a main package
+	File     string // name of one of the package's Go files, if known
+
+	RedirectMoved bool   // refresh to the page of MovedTo instead of RepoURL
+	Variant       string // name of the branch variant of a preview page
+	GoImportURL   string // overrides RepoURL in go-import
+	GoSourceURL   string // overrides RepoURL in go-source
+	GoImportVCS   string // version control system in go-import, git if empty
+	GitLab        bool   // source links use GitLab's URL layout
+	Redirect      string // visitors are sent here rather than the repository
+
+	SourceFormat *template.Template // renders go-source URLs instead of GitHub's layout
+}
+
+// ImportURL returns the repository root URL of go-import.
+func (i Import) ImportURL() string {
+	if i.GoImportURL != "" {
+		return i.GoImportURL
+	}
+	return i.RepoURL
+}
+
+// VCS returns the version control system of go-import.
+func (i Import) VCS() string {
+	if i.GoImportVCS != "" {
+		return i.GoImportVCS
+	}
+	return "git"
+}
+
+// SourceURL returns the repository home URL of go-source, which source
+// links are relative to.
+func (i Import) SourceURL() string {
+	if i.GoSourceURL != "" {
+		return i.GoSourceURL
+	}
+	return i.RepoURL
+}
+
+// ImportPrefix returns the import path of the repository root, the
+// import path without the last PathLen segments, such as pack.ag/amqp for
+// pack.ag/amqp/internal/encoding with a PathLen of 2. It's empty if the
+// import path has no more segments than PathLen, which happens when a
+// package's import comment is shallower than its directory.
+func (i Import) ImportPrefix() string {
+	importURL, err := url.Parse(i.Import)
+	if err != nil {
+		return ""
+	}
+
+	importPathSegments := strings.Split(importURL.Path, "/")
+	if i.PathLen < 0 || i.PathLen >= len(importPathSegments) {
+		return ""
+	}
+	importURL.Path = strings.Join(importPathSegments[:len(importPathSegments)-i.PathLen], "/")
+
+	return importURL.String()
+}
+
+// SourceDir returns the go-source directory URL template.
+//
+// For a package at the repository root {/dir} expands to an empty string,
+// otherwise it expands to a slash followed by the directory.
+func (i Import) SourceDir() string {
+	if urls, ok := i.customSource(); ok {
+		return urls[1]
+	}
+	tree, _ := i.SourcePaths()
+	return i.WebURL() + tree + i.branch() + i.SubdirPath() + "{/dir}"
+}
+
+// SourceFile returns the go-source file URL template.
+func (i Import) SourceFile() string {
+	if urls, ok := i.customSource(); ok {
+		return urls[2]
+	}
+	_, blob := i.SourcePaths()
+	return i.WebURL() + blob + i.branch() + i.SubdirPath() + "{/dir}/{file}#L{line}"
+}
+
+// SubdirPath returns the subdirectory with a leading slash, if any.
+func (i Import) SubdirPath() string {
+	if i.Subdir == "" {
+		return ""
+	}
+	return "/" + i.Subdir
+}
+
+// WebURL returns the repository URL without a trailing slash or .git
+// suffix, so that paths can be appended to it.
+func (i Import) WebURL() string {
+	return strings.TrimSuffix(strings.TrimSuffix(i.SourceURL(), "/"), ".git")
+}
+
+// IssuesURL returns the URL of the repository's issue tracker.
+func (i Import) IssuesURL() string {
+	if i.GitLab {
+		return i.WebURL() + "/-/issues"
+	}
+	return i.WebURL() + "/issues"
+}
+
+// branch returns the branch go-source URLs refer to.
+func (i Import) branch() string {
+	if i.Branch == "" {
+		return "master"
+	}
+	return i.Branch
+}
+
+// Dir returns the package's directory relative to the directory of
+// ImportPrefix, normally the repository root.
+func (i Import) Dir() string {
+	segments := strings.Split(i.Import, "/")
+	if i.PathLen <= 0 || i.PathLen >= len(segments) {
+		return ""
+	}
+	return strings.Join(segments[len(segments)-i.PathLen:], "/")
+}
+
+// RefreshURL returns the URL visitors are sent to.
+func (i Import) RefreshURL() string {
+	switch {
+	case i.MovedTo != "" && i.RedirectMoved:
+		return "https://" + i.MovedTo
+	case i.Variant != "":
+		dir := i.Dir()
+		if dir != "" {
+			dir = "/" + dir
+		}
+		return strings.Replace(i.SourceDir(), "{/dir}", dir, 1)
+	}
+	return i.Home()
+}
+
+// Home returns the go-source home URL, which visitors are sent to.
+func (i Import) Home() string {
+	if i.Redirect != "" {
+		return i.Redirect
+	}
+	return i.SourceURL()
+}
+
+// SourceHome returns the go-source home URL, which is Home unless set by
+// -go-source-format.
+func (i Import) SourceHome() string {
+	if urls, ok := i.customSource(); ok {
+		return urls[0]
+	}
+	return i.Home()
+}
+
+// Description returns the package synopsis, falling back to the
+// import path for undocumented packages.
+func (i Import) Description() string {
+	if i.Synopsis != "" {
+		return i.Synopsis
+	}
+	return i.Import
+}
+
+// VariantSuffix returns the suffix of the page paths of a branch variant.
+func (i Import) VariantSuffix() string {
+	if i.Variant == "" {
+		return ""
+	}
+	return "@" + i.Variant
+}
+
+// SourcePaths returns the path segments which precede the branch in the
+// directory and file URLs of the repository's web interface.
+func (i Import) SourcePaths() (tree, blob string) {
+	if i.GitLab {
+		return "/-/tree/", "/-/blob/"
+	}
+	return "/tree/", "/blob/"
+}
@@ -0,0 +1,91 @@
+package vanity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportPrefix(t *testing.T) {
+	tests := []struct {
+		imprt   string
+		pathLen int
+		want    string
+	}{
+		{imprt: "pack.ag/amqp/internal/encoding", pathLen: 2, want: "pack.ag/amqp"},
+		{imprt: "pack.ag/amqp/internal/encoding", pathLen: 3, want: "pack.ag"},
+		{imprt: "pack.ag/amqp/v2/internal", pathLen: 1, want: "pack.ag/amqp/v2"},
+		{imprt: "example.com/go/amqp/cmd/amqp", pathLen: 2, want: "example.com/go/amqp"},
+		// A package at the repository root.
+		{imprt: "pack.ag/amqp", pathLen: 0, want: "pack.ag/amqp"},
+		{imprt: "pack.ag", pathLen: 0, want: "pack.ag"},
+		// A single segment below the root.
+		{imprt: "pack.ag/amqp/cmd", pathLen: 1, want: "pack.ag/amqp"},
+		// An import comment shallower than the directory.
+		{imprt: "pack.ag/amqp", pathLen: 2, want: ""},
+		{imprt: "pack.ag/amqp", pathLen: 5, want: ""},
+		{imprt: "pack.ag", pathLen: 1, want: ""},
+		{imprt: "pack.ag/amqp", pathLen: -1, want: ""},
+	}
+	for _, tt := range tests {
+		imprt := Import{Import: tt.imprt, PathLen: tt.pathLen}
+		if got := imprt.ImportPrefix(); got != tt.want {
+			t.Errorf("ImportPrefix(%q, %d) = %q, want %q", tt.imprt, tt.pathLen, got, tt.want)
+		}
+	}
+}
+
+func TestSourceURLs(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name     string
+		imprt    Import
+		wantDir  string
+		wantFile string
+	}{
+		{
+			name:     "branch",
+			imprt:    Import{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "develop"},
+			wantDir:  "https://github.com/vcabbage/tftp/tree/develop{/dir}",
+			wantFile: "https://github.com/vcabbage/tftp/blob/develop{/dir}/{file}#L{line}",
+		},
+		{
+			// The commit of a repository pinned by the -config file
+			// is scanned, so links must not follow the branch.
+			name:     "pinned commit",
+			imprt:    Import{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: sha},
+			wantDir:  "https://github.com/vcabbage/tftp/tree/" + sha + "{/dir}",
+			wantFile: "https://github.com/vcabbage/tftp/blob/" + sha + "{/dir}/{file}#L{line}",
+		},
+		{
+			name:     "github root",
+			imprt:    Import{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp.git", Branch: "master"},
+			wantDir:  "https://github.com/vcabbage/tftp/tree/master{/dir}",
+			wantFile: "https://github.com/vcabbage/tftp/blob/master{/dir}/{file}#L{line}",
+		},
+		{
+			name:     "gitlab root",
+			imprt:    Import{Import: "pack.ag/tftp", RepoURL: "https://gitlab.com/vcabbage/tftp/", Branch: "master", GitLab: true},
+			wantDir:  "https://gitlab.com/vcabbage/tftp/-/tree/master{/dir}",
+			wantFile: "https://gitlab.com/vcabbage/tftp/-/blob/master{/dir}/{file}#L{line}",
+		},
+	}
+	for _, tt := range tests {
+		dir, file := tt.imprt.SourceDir(), tt.imprt.SourceFile()
+		if dir != tt.wantDir {
+			t.Errorf("%s: SourceDir() = %q, want %q", tt.name, dir, tt.wantDir)
+		}
+		if file != tt.wantFile {
+			t.Errorf("%s: SourceFile() = %q, want %q", tt.name, file, tt.wantFile)
+		}
+
+		// {/dir} expands to nothing for a package at the root, which
+		// mustn't leave a trailing or doubled slash.
+		dir = strings.Replace(dir, "{/dir}", "", 1)
+		file = strings.NewReplacer("{/dir}", "", "{file}", "tftp.go", "{line}", "1").Replace(file)
+		for _, u := range []string{dir, file} {
+			if strings.HasSuffix(u, "/") || strings.Contains(strings.TrimPrefix(u, "https://"), "//") {
+				t.Errorf("%s: root package URL %q has a stray slash", tt.name, u)
+			}
+		}
+	}
+}
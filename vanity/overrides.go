@@ -0,0 +1,117 @@
+package vanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// OverridesFile is the name of the file at the root of a repository
+// which declares the repository's vanity imports explicitly.
+const OverridesFile = ".govanity.json"
+
+// Overrides is the contents of an OverridesFile.
+//
+// Example:
+//
+//	{
+//	  "packages": [
+//	    {"import": "pack.ag/tftp", "dir": "."},
+//	    {"import": "pack.ag/tftp/netascii", "dir": "netascii"}
+//	  ]
+//	}
+type Overrides struct {
+	Packages []struct {
+		Import string `json:"import"`
+		Dir    string `json:"dir"`
+	} `json:"packages"`
+}
+
+// ParseOverrides parses the contents of an OverridesFile.
+func ParseOverrides(data []byte) (*Overrides, error) {
+	var o Overrides
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", OverridesFile, err)
+	}
+	return &o, nil
+}
+
+// ReadOverrides reads the OverridesFile in dir. A nil *Overrides is
+// returned if the file doesn't exist.
+func ReadOverrides(dir string) (*Overrides, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, OverridesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseOverrides(data)
+}
+
+// Imports returns the imports of the repository at repoURL declared in
+// o which match one of cfg.Prefixes, and those which were skipped
+// because they don't.
+func (o *Overrides) Imports(cfg Config, repoURL string) ([]Import, []Skipped, error) {
+	var (
+		imports []Import
+		skipped []Skipped
+	)
+	for _, pkg := range o.Packages {
+		pkg.Import = CleanImportPath(pkg.Import)
+		if MatchPrefix(cfg.Prefixes, pkg.Import, cfg.CaseInsensitive) == "" {
+			skipped = append(skipped, Skipped{Path: pkg.Import, Reason: "non-matching prefix"})
+			continue
+		}
+
+		dir := path.Clean(filepath.ToSlash(pkg.Dir))
+		if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, nil, fmt.Errorf("%s: directory %q is outside of the repository", OverridesFile, pkg.Dir)
+		}
+
+		pathLen := 0
+		if dir != "." {
+			pathLen = len(strings.Split(dir, "/"))
+		}
+
+		imports = append(imports, Import{
+			Import:  pkg.Import,
+			RepoURL: repoURL,
+			PathLen: pathLen,
+		})
+	}
+	return imports, skipped, nil
+}
+
+// ApplyRoots sets the import prefix of imports within one of roots,
+// which maps subdirectories of their repository to the import prefix of
+// the packages within them, using the root with the longest matching
+// import prefix.
+func ApplyRoots(imports []Import, roots map[string]string) {
+	for i, imprt := range imports {
+		var subdir, prefix string
+		for dir, p := range roots {
+			p = strings.TrimSuffix(p, "/")
+			if imprt.Import != p && !strings.HasPrefix(imprt.Import, p+"/") {
+				continue
+			}
+			if len(p) > len(prefix) {
+				subdir, prefix = dir, p
+			}
+		}
+		if prefix == "" {
+			continue
+		}
+
+		subdir = strings.TrimPrefix(path.Clean(filepath.ToSlash(subdir)), "/")
+		if subdir == "." {
+			subdir = ""
+		}
+		imports[i].Subdir = subdir
+		imports[i].PathLen = len(strings.Split(imprt.Import, "/")) - len(strings.Split(prefix, "/"))
+	}
+}
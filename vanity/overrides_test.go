@@ -0,0 +1,40 @@
+package vanity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyRoots(t *testing.T) {
+	// One repository holding the packages of two import prefixes, one
+	// nested within the other's.
+	roots := map[string]string{
+		"go":         "pack.ag/sctp",
+		"/go/proto/": "pack.ag/sctp/proto/",
+	}
+	imports := []Import{
+		{Import: "pack.ag/sctp", PathLen: 1},
+		{Import: "pack.ag/sctp/chunk", PathLen: 2},
+		{Import: "pack.ag/sctp/proto", PathLen: 2},
+		{Import: "pack.ag/sctp/proto/wire", PathLen: 3},
+		{Import: "pack.ag/tftp", PathLen: 0},
+	}
+	ApplyRoots(imports, roots)
+
+	want := []Import{
+		{Import: "pack.ag/sctp", Subdir: "go"},
+		{Import: "pack.ag/sctp/chunk", Subdir: "go", PathLen: 1},
+		{Import: "pack.ag/sctp/proto", Subdir: "go/proto"},
+		{Import: "pack.ag/sctp/proto/wire", Subdir: "go/proto", PathLen: 1},
+		{Import: "pack.ag/tftp"},
+	}
+	if !reflect.DeepEqual(imports, want) {
+		t.Errorf("imports = %+v, want %+v", imports, want)
+	}
+	wantPrefixes := []string{"pack.ag/sctp", "pack.ag/sctp", "pack.ag/sctp/proto", "pack.ag/sctp/proto", "pack.ag/tftp"}
+	for i, imprt := range imports {
+		if got := imprt.ImportPrefix(); got != wantPrefixes[i] {
+			t.Errorf("%s: ImportPrefix() = %q, want %q", imprt.Import, got, wantPrefixes[i])
+		}
+	}
+}
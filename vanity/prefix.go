@@ -0,0 +1,29 @@
+package vanity
+
+import "strings"
+
+// MatchPrefix returns the longest of prefixes which importPath begins
+// with, ignoring case if fold is set, or an empty string if there isn't
+// one.
+func MatchPrefix(prefixes []string, importPath string, fold bool) string {
+	var match string
+	for _, prefix := range prefixes {
+		if HasImportPrefix(importPath, prefix, fold) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	return match
+}
+
+// HasImportPrefix reports whether importPath begins with prefix, ignoring
+// case if fold is set. Import paths are case sensitive, but their hosts
+// are lower cased by CleanImportPath so that they match regardless.
+func HasImportPrefix(importPath, prefix string, fold bool) bool {
+	if len(importPath) < len(prefix) {
+		return false
+	}
+	if fold {
+		return strings.EqualFold(importPath[:len(prefix)], prefix)
+	}
+	return importPath[:len(prefix)] == prefix
+}
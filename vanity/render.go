@@ -0,0 +1,87 @@
+package vanity
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Icons are the URL paths of the icons referenced by pages.
+type Icons struct {
+	Favicon        string
+	AppleTouchIcon string
+}
+
+// IconsTemplate defines the "icons" template, which links the icons of
+// an Icons. It's available to PageTemplate and ParsePageTemplate.
+const IconsTemplate = `{{define "icons"}}
+{{- with .Favicon}}
+  <link rel="icon" href="{{.}}">
+{{- end}}
+{{- with .AppleTouchIcon}}
+  <link rel="apple-touch-icon" href="{{.}}">
+{{- end}}
+{{- end}}`
+
+// PageTemplate is the default template of the page of an Import.
+var PageTemplate = template.Must(template.Must(template.New("tmpl").Parse(IconsTemplate)).Parse(`<!DOCTYPE html>
+<head>
+  <meta http-equiv="content-type" content="text/html; charset=utf-8">
+{{- if .OpenGraph}}
+  <meta name="description" content="{{.Description}}">
+  <meta property="og:title" content="{{.Import}}">
+  <meta property="og:description" content="{{.Description}}">
+{{- end}}
+{{- template "icons" .Icons}}
+  <meta name="go-import" content="{{.ImportPrefix}} {{.VCS}} {{.ImportURL}}{{with .Subdir}} {{.}}{{end}}">
+{{- if eq .VCS "git"}}
+  <meta name="go-source" content="{{.ImportPrefix}} {{.SourceHome}} {{.SourceDir}} {{.SourceFile}}">
+{{- end}}
+  <meta http-equiv="refresh" content="0; url={{.RefreshURL}}">
+</head>
+{{- with .MovedTo}}
+<body>
+  <p>This package has moved to <a href="https://{{.}}">{{.}}</a>.</p>
+</body>
+{{- end}}
+</html>
+`))
+
+// ParsePageTemplate parses the page template in the file at path. Like
+// PageTemplate, it may use the "icons" template.
+func ParsePageTemplate(path string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.Must(template.New(filepath.Base(path)).Parse(IconsTemplate)).Parse(string(data))
+}
+
+// RenderOptions configure how the page of an Import is rendered.
+type RenderOptions struct {
+	Template  *template.Template // PageTemplate if nil
+	OpenGraph bool               // include Open Graph tags
+	Icons     Icons
+}
+
+// pageImport names the Import embedded in page, so that the page's
+// {{.Import}} is the import path rather than the Import itself.
+type pageImport = Import
+
+// page is the data passed to the page template.
+type page struct {
+	pageImport
+	OpenGraph bool
+	Icons     Icons
+}
+
+// RenderHTML writes the page of i to w, which the go command fetches to
+// resolve its import path.
+func (i Import) RenderHTML(w io.Writer, opts RenderOptions) error {
+	t := opts.Template
+	if t == nil {
+		t = PageTemplate
+	}
+	return t.Execute(w, page{pageImport: i, OpenGraph: opts.OpenGraph, Icons: opts.Icons})
+}
@@ -0,0 +1,58 @@
+package vanity
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	imprt := Import{
+		Import:   "pack.ag/amqp/internal/encoding",
+		RepoURL:  "https://github.com/vcabbage/amqp",
+		Branch:   "master",
+		Synopsis: "Package encoding encodes AMQP types.",
+		PathLen:  2,
+	}
+
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want []string
+	}{
+		{
+			name: "default",
+			want: []string{
+				`<meta name="go-import" content="pack.ag/amqp git https://github.com/vcabbage/amqp">`,
+				`<meta name="go-source" content="pack.ag/amqp https://github.com/vcabbage/amqp https://github.com/vcabbage/amqp/tree/master{/dir} https://github.com/vcabbage/amqp/blob/master{/dir}/{file}#L{line}">`,
+				`<meta http-equiv="refresh" content="0; url=https://github.com/vcabbage/amqp">`,
+			},
+		},
+		{
+			name: "open graph and icons",
+			opts: RenderOptions{OpenGraph: true, Icons: Icons{Favicon: "/favicon.ico"}},
+			want: []string{
+				`<meta property="og:title" content="pack.ag/amqp/internal/encoding">`,
+				`<meta property="og:description" content="Package encoding encodes AMQP types.">`,
+				`<link rel="icon" href="/favicon.ico">`,
+			},
+		},
+		{
+			name: "template",
+			opts: RenderOptions{Template: template.Must(template.New("page").Parse(`{{.Import}} {{.ImportPrefix}}`))},
+			want: []string{"pack.ag/amqp/internal/encoding pack.ag/amqp"},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := imprt.RenderHTML(&buf, tt.opts); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: page doesn't contain %s:\n%s", tt.name, want, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-github/github"
+)
+
+// Repository is a repository as returned by the GitHub API, including
+// fields which the vendored client doesn't support.
+type Repository struct {
+	github.Repository
+	IsTemplate *bool `json:"is_template,omitempty"`
+	Archived   *bool `json:"archived,omitempty"`
+}
+
+// GetIsTemplate returns whether the repository is a template repository.
+func (r *Repository) GetIsTemplate() bool {
+	return r.IsTemplate != nil && *r.IsTemplate
+}
+
+// GetArchived returns whether the repository is archived.
+func (r *Repository) GetArchived() bool {
+	return r.Archived != nil && *r.Archived
+}
+
+// ListRepositories is the equivalent of gh.Repositories.List for a user,
+// returning the repositories with all fields.
+func ListRepositories(ctx context.Context, gh *github.Client, user string, opt *github.RepositoryListOptions) ([]*Repository, *github.Response, error) {
+	q := url.Values{}
+	if opt.PerPage != 0 {
+		q.Set("per_page", fmt.Sprint(opt.PerPage))
+	}
+	if opt.Page != 0 {
+		q.Set("page", fmt.Sprint(opt.Page))
+	}
+
+	u := fmt.Sprintf("users/%v/repos", url.PathEscape(user))
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := gh.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var repos []*Repository
+	resp, err := gh.Do(ctx, req, &repos)
+	if err != nil {
+		return nil, resp, err
+	}
+	return repos, resp, nil
+}
+
+// SearchRepositories is the equivalent of gh.Search.Repositories,
+// returning the repositories with all fields.
+func SearchRepositories(ctx context.Context, gh *github.Client, query string, opt *github.SearchOptions) ([]*Repository, *github.Response, error) {
+	q := url.Values{"q": {query}}
+	if opt.PerPage != 0 {
+		q.Set("per_page", fmt.Sprint(opt.PerPage))
+	}
+	if opt.Page != 0 {
+		q.Set("page", fmt.Sprint(opt.Page))
+	}
+
+	req, err := gh.NewRequest("GET", "search/repositories?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Items []*Repository `json:"items"`
+	}
+	resp, err := gh.Do(ctx, req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+	return result.Items, resp, nil
+}
+
+// RepoFilter selects the listed repositories which are scanned.
+type RepoFilter struct {
+	IncludeForks     bool
+	ExcludeArchived  bool
+	IncludeTemplates bool
+}
+
+// SkipReason returns why r isn't scanned, or an empty string if it is.
+func (f RepoFilter) SkipReason(r *Repository) string {
+	switch {
+	case r.GetFork() && !f.IncludeForks:
+		return "is a fork"
+	case r.GetArchived() && f.ExcludeArchived:
+		return "is archived"
+	case r.GetIsTemplate() && !f.IncludeTemplates:
+		return "is a template"
+	}
+	return ""
+}
+
+// GoRepos returns the Go repositories of the GitHub user which filter
+// doesn't skip. Their checkouts can be scanned with Discover, using each
+// repository's SVNURL as the Repo URL.
+func GoRepos(ctx context.Context, gh *github.Client, user string, filter RepoFilter) ([]*Repository, error) {
+	var found []*Repository
+	opt := &github.RepositoryListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		repos, resp, err := ListRepositories(ctx, gh, user, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if filter.SkipReason(repo) != "" {
+				continue
+			}
+			if repo.GetLanguage() != "Go" {
+				languages, _, err := gh.Repositories.ListLanguages(ctx, user, repo.GetName())
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", repo.GetFullName(), err)
+				}
+				if _, ok := languages["Go"]; !ok {
+					continue
+				}
+			}
+			found = append(found, repo)
+		}
+		if resp.NextPage == 0 {
+			return found, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,74 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGoRepos(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/vcabbage/repos":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s/users/vcabbage/repos?page=2>; rel="next"`, r.Host))
+				fmt.Fprint(w, `[
+					{"name": "tftp", "language": "Go"},
+					{"name": "amqp", "language": "Shell"},
+					{"name": "www", "language": "HTML"}
+				]`)
+				return
+			}
+			fmt.Fprint(w, `[
+				{"name": "sctp", "language": "Go", "fork": true},
+				{"name": "old", "language": "Go", "archived": true},
+				{"name": "template", "language": "Go", "is_template": true}
+			]`)
+		case "/repos/vcabbage/amqp/languages":
+			fmt.Fprint(w, `{"Shell": 100, "Go": 10}`)
+		case "/repos/vcabbage/www/languages":
+			fmt.Fprint(w, `{"HTML": 100}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	gh := github.NewClient(nil)
+	var err error
+	if gh.BaseURL, err = url.Parse(srv.URL + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter RepoFilter
+		want   []string
+	}{
+		{name: "default", want: []string{"tftp", "amqp", "old"}},
+		{name: "forks", filter: RepoFilter{IncludeForks: true}, want: []string{"tftp", "amqp", "sctp", "old"}},
+		{name: "archived", filter: RepoFilter{ExcludeArchived: true}, want: []string{"tftp", "amqp"}},
+		{name: "templates", filter: RepoFilter{IncludeTemplates: true}, want: []string{"tftp", "amqp", "old", "template"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := GoRepos(context.Background(), gh, "vcabbage", tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, repo := range repos {
+				got = append(got, repo.GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GoRepos() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
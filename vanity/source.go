@@ -0,0 +1,64 @@
+package vanity
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// SourceData is the data of the template of Import.SourceFormat.
+type SourceData struct {
+	RepoURL string // without a trailing slash or .git suffix
+	Branch  string
+	Subdir  string // directory of the import prefix with a leading slash, if any
+}
+
+// ParseSourceFormat returns the template of Import.SourceFormat parsed
+// from format, or nil if it's empty, checking that it renders the three
+// URLs of go-source separated by spaces.
+func ParseSourceFormat(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+	t, err := template.New("go-source").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := renderSource(t, SourceData{RepoURL: "https://example.com/repo", Branch: "master"}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// renderSource returns the home, directory, and file URLs rendered by t
+// with data.
+func renderSource(t *template.Template, data SourceData) ([]string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	urls := strings.Fields(buf.String())
+	if len(urls) != 3 {
+		return nil, fmt.Errorf("expected home, directory, and file URLs separated by spaces, got %q", buf.String())
+	}
+	return urls, nil
+}
+
+// customSource returns the go-source URLs of i rendered with its
+// SourceFormat, reporting whether it was set.
+func (i Import) customSource() ([]string, bool) {
+	if i.SourceFormat == nil {
+		return nil, false
+	}
+	urls, err := renderSource(i.SourceFormat, SourceData{
+		RepoURL: i.WebURL(),
+		Branch:  i.branch(),
+		Subdir:  i.SubdirPath(),
+	})
+	// The template always renders when ParseSourceFormat accepts it.
+	if err != nil {
+		return nil, false
+	}
+	return urls, true
+}
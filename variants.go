@@ -0,0 +1,34 @@
+package main
+
+import (
+	"sort"
+)
+
+// branchVariants returns a preview page of each import for each of the
+// branch variants configured for its repository. A variant's page is at
+// the import path suffixed with @ and the variant name, and its source
+// links point at the variant's branch. Imports of major version branches
+// don't get variants.
+func (cfg *config) branchVariants(imports []vanityImport) []vanityImport {
+	var variants []vanityImport
+	for _, imprt := range imports {
+		rc := cfg.repos[imprt.RepoURL]
+		if len(rc.Variants) == 0 || majorBranchRE.MatchString(imprt.Branch) || imprt.MovedTo != "" {
+			continue
+		}
+
+		var names []string
+		for name := range rc.Variants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			v := imprt
+			v.Branch = rc.Variants[name]
+			v.Variant = name
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestBranchVariantPages(t *testing.T) {
+	for _, layout := range []string{layoutFile, layoutDir} {
+		cfg := testConfig("pack.ag")
+		cfg.pageTmpl = vanity.PageTemplate
+		cfg.layout = layout
+		cfg.rootBehavior = rootNone
+		cfg.repos = map[string]repoConfig{
+			"https://github.com/vcabbage/amqp": {Variants: map[string]string{"dev": "develop"}},
+		}
+		dir := t.TempDir()
+		imports := []vanityImport{
+			{Import: "pack.ag/amqp", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master"},
+			{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		}
+		if _, err := generate(context.Background(), cfg, dir, imports); err != nil {
+			t.Fatal(err)
+		}
+
+		pages := map[string]string{
+			"amqp":     "https://github.com/vcabbage/amqp/tree/master{/dir}",
+			"amqp@dev": "https://github.com/vcabbage/amqp/tree/develop{/dir}",
+			"tftp":     "https://github.com/vcabbage/tftp/tree/master{/dir}",
+		}
+		for name, sourceDir := range pages {
+			path := filepath.Join(dir, name+".html")
+			if layout == layoutDir {
+				path = filepath.Join(dir, name, "index.html")
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Errorf("%s: %v", layout, err)
+				continue
+			}
+			meta, err := parseMeta(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The preview resolves the same import, only its source
+			// links differ.
+			if got := meta["go-import"]; !strings.HasPrefix(got, "pack.ag/"+strings.TrimSuffix(name, "@dev")+" git ") {
+				t.Errorf("%s: %s go-import = %q", layout, name, got)
+			}
+			if got := strings.Fields(meta["go-source"]); len(got) != 4 || got[2] != sourceDir {
+				t.Errorf("%s: %s go-source = %q, want directory %s", layout, name, meta["go-source"], sourceDir)
+			}
+		}
+		if _, err := ioutil.ReadFile(filepath.Join(dir, "tftp@dev.html")); err == nil {
+			t.Errorf("%s: tftp has a preview without a variant", layout)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifyInterval is the minimum time between requests made by verifySource.
+const verifyInterval = 500 * time.Millisecond
+
+// verifySource issues HEAD requests for the go-source directory URL of one
+// package per repository, and the file URL of one of its Go files if it's
+// known, reporting any that don't respond with 200 OK.
+func verifySource(ctx context.Context, client *http.Client, imports []vanityImport) error {
+	seen := make(map[string]struct{})
+	var urls []string
+	for _, imprt := range imports {
+		if _, ok := seen[imprt.RepoURL]; ok {
+			continue
+		}
+		seen[imprt.RepoURL] = struct{}{}
+
+		dir := imprt.Dir()
+		if dir != "" {
+			dir = "/" + dir
+		}
+		urls = append(urls, strings.Replace(imprt.SourceDir(), "{/dir}", dir, 1))
+		if imprt.File != "" {
+			// The line is only a fragment, which isn't requested.
+			file := strings.NewReplacer("{/dir}", dir, "{file}", imprt.File, "#L{line}", "").Replace(imprt.SourceFile())
+			urls = append(urls, file)
+		}
+	}
+
+	var failed []string
+	for i, u := range urls {
+		if i > 0 {
+			select {
+			case <-time.After(verifyInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		status, err := headStatus(ctx, client, u)
+		if err != nil {
+			warnf("", "verify %s: %v", u, err)
+			failed = append(failed, u)
+			continue
+		}
+		if status != http.StatusOK {
+			warnf("", "verify %s: %d %s", u, status, http.StatusText(status))
+			failed = append(failed, u)
+			continue
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d go-source URLs failed verification", len(failed), len(urls))
+	}
+	logf("Verified %d go-source URLs.\n", len(urls))
+	return nil
+}
+
+// headStatus returns the status code of a HEAD request to u. If the server
+// responds with 429 Too Many Requests the request is retried once after
+// waiting for the duration indicated by Retry-After.
+func headStatus(ctx context.Context, client *http.Client, u string) (int, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodHead, u, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt > 0 {
+			return resp.StatusCode, nil
+		}
+
+		wait := time.Minute
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(secs) * time.Second
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestVerifySource(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/vcabbage/tftp/tree/master/netascii", "/vcabbage/tftp/blob/master/netascii/netascii.go":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		imports []vanityImport
+		want    []string
+		ok      bool
+	}{
+		{
+			name: "directory and file",
+			imports: []vanityImport{
+				{Import: "pack.ag/tftp/netascii", RepoURL: srv.URL + "/vcabbage/tftp", Branch: "master", PathLen: 1, File: "netascii.go"},
+				// Only one package per repository is checked.
+				{Import: "pack.ag/tftp", RepoURL: srv.URL + "/vcabbage/tftp", Branch: "master"},
+			},
+			want: []string{
+				"HEAD /vcabbage/tftp/blob/master/netascii/netascii.go",
+				"HEAD /vcabbage/tftp/tree/master/netascii",
+			},
+			ok: true,
+		},
+		{
+			name: "missing file",
+			imports: []vanityImport{
+				{Import: "pack.ag/tftp/netascii", RepoURL: srv.URL + "/vcabbage/tftp", Branch: "master", PathLen: 1, File: "missing.go"},
+			},
+			want: []string{
+				"HEAD /vcabbage/tftp/blob/master/netascii/missing.go",
+				"HEAD /vcabbage/tftp/tree/master/netascii",
+			},
+		},
+		{
+			name: "wrong branch",
+			imports: []vanityImport{
+				{Import: "pack.ag/tftp/netascii", RepoURL: srv.URL + "/vcabbage/tftp", Branch: "main", PathLen: 1},
+			},
+			want: []string{"HEAD /vcabbage/tftp/tree/main/netascii"},
+		},
+	}
+	for _, tt := range tests {
+		requested = nil
+		err := verifySource(context.Background(), srv.Client(), tt.imports)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: verifySource() = %v, want ok %t", tt.name, err, tt.ok)
+		}
+		sort.Strings(requested)
+		if !reflect.DeepEqual(requested, tt.want) {
+			t.Errorf("%s: requested %q, want %q", tt.name, requested, tt.want)
+		}
+	}
+}
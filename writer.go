@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of fileChange.
+const (
+	changeCreated  = "created"
+	changeModified = "modified"
+	changeDeleted  = "deleted"
+)
+
+// fileChange records a file which was changed by a run.
+type fileChange struct {
+	path string // relative to the output directory
+	kind string
+}
+
+// siteWriter writes generated files to dir. Files are only written when
+// their contents change, and each change is recorded.
+type siteWriter struct {
+	dir         string
+	postProcess string // command HTML is piped through before it's written
+	changes     []fileChange
+	hashes      map[string]string // hex SHA-256 of every generated file by relative path
+	kept        map[string]bool   // generated files which weren't written, by relative path
+}
+
+// writeTemplate executes t with data, writing the result to path.
+func (w *siteWriter) writeTemplate(ctx context.Context, path string, t *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	html, err := postProcess(ctx, w.postProcess, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return w.writeFile(path, html)
+}
+
+// postProcess returns html piped through command, or html unchanged if
+// command is empty. The command is killed if ctx is done.
+func postProcess(ctx context.Context, command string, html []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return html, nil
+	}
+
+	cmd := commandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(html)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("post-processing: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// writeFile writes data to path if it differs from the current contents.
+func (w *siteWriter) writeFile(path string, data []byte) error {
+	kind := changeModified
+	existing, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		kind = changeCreated
+	case err != nil:
+		return err
+	case bytes.Equal(existing, data):
+		w.hash(path, data)
+		return nil
+	}
+
+	if err := writeFile(path, data); err != nil {
+		return err
+	}
+	w.record(path, kind)
+	w.hash(path, data)
+	return nil
+}
+
+// hash records the hash of data, the contents of the file at path.
+func (w *siteWriter) hash(path string, data []byte) {
+	if w.hashes == nil {
+		w.hashes = make(map[string]string)
+	}
+	sum := sha256.Sum256(data)
+	w.hashes[w.rel(path)] = hex.EncodeToString(sum[:])
+}
+
+// keep records that the file at path wasn't written, because of an
+// error or -respect-gitignore, so that any existing file isn't pruned.
+func (w *siteWriter) keep(path string) {
+	if w.kept == nil {
+		w.kept = make(map[string]bool)
+	}
+	w.kept[w.rel(path)] = true
+}
+
+// record adds a change of kind to the file at path.
+func (w *siteWriter) record(path, kind string) {
+	w.changes = append(w.changes, fileChange{path: w.rel(path), kind: kind})
+}
+
+// rel returns path relative to the output directory.
+func (w *siteWriter) rel(path string) string {
+	if rel, err := filepath.Rel(w.dir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// writeChanges writes each change as a line containing the kind of
+// change and the path to the file at path, or stdout if path is "-".
+func writeChanges(path string, changes []fileChange) error {
+	var buf bytes.Buffer
+	for _, c := range changes {
+		fmt.Fprintf(&buf, "%s %s\n", c.kind, c.path)
+	}
+
+	if path == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeIntegrity writes hashes to path in the format of sha256sum, so the
+// files can be checked with sha256sum -c from the output directory.
+func writeIntegrity(path string, hashes map[string]string) error {
+	var paths []string
+	for p := range hashes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	for _, p := range paths {
+		fmt.Fprintf(&buf, "%s  %s\n", hashes[p], p)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
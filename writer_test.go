@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pack.ag/cmd/govanity/vanity"
+)
+
+func TestPostProcess(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		ctx     context.Context
+		command string
+		want    string
+		err     bool
+	}{
+		{ctx: context.Background(), command: "", want: "<html></html>"},
+		{ctx: context.Background(), command: "tr a-z A-Z", want: "<HTML></HTML>"},
+		{ctx: context.Background(), command: "false", err: true},
+		{ctx: cancelled, command: "tr a-z A-Z", err: true},
+	}
+	for _, tt := range tests {
+		got, err := postProcess(tt.ctx, tt.command, []byte("<html></html>"))
+		if (err != nil) != tt.err || string(got) != tt.want {
+			t.Errorf("postProcess(%q) = %q, %v, want %q, error %t", tt.command, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPostProcessEverywhere(t *testing.T) {
+	imprt := vanityImport{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"}
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.layout = layoutFile
+	cfg.postProcess = "tr a-z A-Z"
+
+	html, err := cfg.renderPage(context.Background(), imprt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(html, []byte("PACK.AG/TFTP")) {
+		t.Errorf("rendered page wasn't post-processed:\n%s", html)
+	}
+
+	// The self test checks the page as it's written, which the
+	// filter breaks.
+	if err := selfTest(context.Background(), cfg, []vanityImport{imprt}); err == nil {
+		t.Error("selfTest passed for a page without meta tags")
+	}
+
+	// Normalizing leaves pages as the filter wrote them.
+	cfg.postProcess = `tr -d \n`
+	cfg.out = t.TempDir()
+	if html, err = cfg.renderPage(context.Background(), imprt); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(html, []byte("\n")) {
+		t.Fatalf("rendered page wasn't post-processed:\n%s", html)
+	}
+	path := filepath.Join(cfg.out, "tftp.html")
+	writeTestFiles(t, cfg.out, map[string]string{"tftp.html": string(html)})
+	if err := normalize(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, html) {
+		t.Errorf("normalized page = %q, %v, want %q", got, err, html)
+	}
+}
+
+func TestWriteChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"same.html":    testPage,
+		"changed.html": "<html></html>",
+		"stale.html":   testPage,
+	})
+
+	w := &siteWriter{dir: dir}
+	for _, name := range []string{"same.html", "changed.html", "new/a.html"} {
+		if err := w.writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(testPage)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "changes")
+	if err := writeChanges(path, w.changes); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "modified changed.html\ncreated new/a.html\ndeleted stale.html\n"
+	if string(got) != want {
+		t.Errorf("changes =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteIntegrity(t *testing.T) {
+	cfg := testConfig("pack.ag")
+	cfg.pageTmpl = vanity.PageTemplate
+	cfg.layout = layoutFile
+	cfg.rootBehavior = rootIndex
+	cfg.writeCNAME = true
+	dir := t.TempDir()
+	imports := []vanityImport{
+		{Import: "pack.ag/tftp", RepoURL: "https://github.com/vcabbage/tftp", Branch: "master"},
+		{Import: "pack.ag/amqp/internal", RepoURL: "https://github.com/vcabbage/amqp", Branch: "master", PathLen: 1},
+	}
+	w, err := generate(context.Background(), cfg, dir, imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "SHA256SUMS")
+	if err := writeIntegrity(path, w.hashes); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		// The format of sha256sum.
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 {
+			t.Fatalf("malformed line %q", line)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(fields[1])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != fields[0] {
+			t.Errorf("%s: hash %s, want %x", fields[1], fields[0], sum)
+		}
+		files[fields[1]] = true
+	}
+	for _, name := range []string{"tftp.html", "amqp/internal.html", "index.html", "CNAME"} {
+		if !files[name] {
+			t.Errorf("%s isn't in the manifest:\n%s", name, data)
+		}
+	}
+}